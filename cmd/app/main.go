@@ -4,32 +4,68 @@ import (
 	"discount/db"
 	"discount/handler"
 	"discount/internal/config"
+	"discount/internal/deps"
 	"discount/internal/locale"
 	"discount/internal/logger"
 	"discount/server"
+	auditService "discount/service/audit"
+	campaignService "discount/service/campaign"
+	checkoutService "discount/service/checkout"
+	discountService "discount/service/discount"
+	exportService "discount/service/export"
 	giftService "discount/service/gift"
+	idempotencyService "discount/service/idempotency"
+	importerService "discount/service/importer"
+	jobService "discount/service/job"
+	redemptionService "discount/service/redemption"
+	auditStorage "discount/storage/audit"
+	campaignStorage "discount/storage/campaign"
 	discountStorage "discount/storage/discount"
 	giftStorage "discount/storage/gift"
+	idempotencyStorage "discount/storage/idempotency"
+	jobStorage "discount/storage/job"
+	redemptionStorage "discount/storage/redemption"
 	"go.uber.org/fx"
 )
 
 func main() {
 	fx.New(
 		fx.Provide(
-			postgresDB,
-			redisDB,
+			deps.PostgresDB,
+			deps.RedisDB,
+			deps.CodeGenerator,
 
 			// Storages
 			giftStorage.New,
 			discountStorage.New,
+			redemptionStorage.New,
+			idempotencyStorage.New,
+			campaignStorage.New,
+			jobStorage.New,
+			auditStorage.New,
 
 			// services
 			giftService.New,
-			//TODO add discount service too
+			discountService.New,
+			checkoutService.New,
+			redemptionService.New,
+			idempotencyService.New,
+			campaignService.New,
+			jobService.New,
+			importerService.New,
+			exportService.New,
+			auditService.New,
 
 			// handlers
 			handler.NewGiftHandler,
-			//TODO add discount handler too
+			handler.NewDiscountHandler,
+			handler.NewCheckoutHandler,
+			handler.NewRedemptionHandler,
+			handler.NewCampaignHandler,
+			handler.NewJobHandler,
+			handler.NewImportHandler,
+			handler.NewExportHandler,
+			handler.NewAuditHandler,
 
 			server.NewServer,
 		),
@@ -41,6 +77,15 @@ func main() {
 			db.Migrate,
 			setupServer,
 			handler.SetupGiftRoutes,
+			handler.SetupDiscountRoutes,
+			handler.SetupCheckoutRoutes,
+			handler.SetupRedemptionRoutes,
+			handler.SetupCampaignRoutes,
+			handler.SetupJobRoutes,
+			handler.SetupImportRoutes,
+			handler.SetupExportRoutes,
+			handler.SetupAuditRoutes,
+			startScheduler,
 			server.Run,
 		),
 	).Run()
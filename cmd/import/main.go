@@ -0,0 +1,95 @@
+// Command import loads existing gift or discount codes from a CSV or JSON Lines file, keeping their used counts.
+//
+//	go run ./cmd/import -type gift [-format csv|jsonl] [-dry-run] [-actor name] codes.csv
+//
+// The format is taken from the file extension unless it is given. Every line is checked first and nothing is
+// written unless all of them are valid; with -dry-run the file is only checked. The lines that cannot be imported
+// are listed on stderr and make the command exit with status 1. The import is recorded in the audit log under the
+// given actor, by default the current user.
+package main
+
+import (
+	"discount/internal/config"
+	"discount/internal/deps"
+	"discount/internal/logger"
+	auditService "discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	"discount/service/importer"
+	auditStorage "discount/storage/audit"
+	campaignStorage "discount/storage/campaign"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	redemptionStorage "discount/storage/redemption"
+	"flag"
+	"fmt"
+	"go.uber.org/fx"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	codeType := flag.String("type", importer.TypeGift, "code type, gift or discount")
+	format := flag.String("format", "", "file format, csv or jsonl; taken from the file extension when empty")
+	dryRun := flag.Bool("dry-run", false, "only check the file")
+	actor := flag.String("actor", os.Getenv("USER"), "who runs the import, for the audit log")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: import -type gift|discount [-format csv|jsonl] [-dry-run] [-actor NAME] FILE")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	var report *importer.ReportDTO
+	run := func(s *importer.Service) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r := &importer.Request{Type: *codeType, Format: *format, DryRun: *dryRun}
+		report, err = s.Import(auditService.Actor{ID: *actor}, r, f)
+		return err
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(
+			deps.PostgresDB,
+			deps.RedisDB,
+			deps.CodeGenerator,
+
+			giftStorage.New,
+			discountStorage.New,
+			redemptionStorage.New,
+			campaignStorage.New,
+			auditStorage.New,
+
+			auditService.New,
+			giftService.New,
+			discountService.New,
+			importer.New,
+		),
+		fx.Invoke(
+			config.Init,
+			logger.SetupLogger,
+			run,
+		),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stderr, "line %d (%s): %s: %s\n", e.Line, e.Code, e.Field, e.Failure.Message)
+	}
+	fmt.Printf("%d records, %d valid, %d imported\n", report.Records, report.Valid, report.Imported)
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Conditions builds the WHERE clause of a query from optional conditions. Each ? in a condition is replaced by
+// the next numbered placeholder, so conditions can be added in any order.
+type Conditions struct {
+	clauses []string
+	args    []any
+}
+
+// Add adds a condition with one argument for each ? it contains.
+func (c *Conditions) Add(clause string, args ...any) {
+	var b strings.Builder
+	for _, part := range strings.SplitAfter(clause, "?") {
+		if strings.HasSuffix(part, "?") {
+			c.args = append(c.args, args[0])
+			args = args[1:]
+			b.WriteString(strings.TrimSuffix(part, "?") + "$" + strconv.Itoa(len(c.args)))
+			continue
+		}
+		b.WriteString(part)
+	}
+	c.clauses = append(c.clauses, "("+b.String()+")")
+}
+
+// Where returns the WHERE clause joining the conditions with AND, or an empty string when there are none.
+func (c *Conditions) Where() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// Args returns the arguments of the conditions in placeholder order.
+func (c *Conditions) Args() []any {
+	return c.args
+}
+
+// LikePrefix returns a LIKE expression matching every string that starts with prefix.
+func LikePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
@@ -0,0 +1,31 @@
+package db_test
+
+import (
+	"discount/db"
+	"reflect"
+	"testing"
+)
+
+func TestConditions(t *testing.T) {
+	var c db.Conditions
+	if where := c.Where(); where != "" {
+		t.Fatalf("expected no where clause, got %q", where)
+	}
+
+	c.Add("created_at >= ?", 1)
+	c.Add("reversed_at IS NULL")
+	c.Add("start_date_time <= ? AND expiration_date > ?", 2, 3)
+	want := " WHERE (created_at >= $1) AND (reversed_at IS NULL) AND (start_date_time <= $2 AND expiration_date > $3)"
+	if where := c.Where(); where != want {
+		t.Fatalf("expected %q, got %q", want, where)
+	}
+	if args := c.Args(); !reflect.DeepEqual(args, []any{1, 2, 3}) {
+		t.Fatalf("unexpected args %v", args)
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	if like := db.LikePrefix(`NOW_50%\`); like != `NOW\_50\%\\%` {
+		t.Fatalf("unexpected like expression %q", like)
+	}
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "redemption";
\ No newline at end of file
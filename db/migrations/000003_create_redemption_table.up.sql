@@ -0,0 +1,16 @@
+CREATE TABLE "redemption"
+(
+    id         SERIAL PRIMARY KEY,
+    code       VARCHAR(255) NOT NULL,
+    code_type  VARCHAR(16)  NOT NULL,
+    user_id    VARCHAR(255) NOT NULL DEFAULT '',
+    order_id   VARCHAR(255) NOT NULL DEFAULT '',
+    amount     DECIMAL(20, 0) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+
+CREATE INDEX ON redemption (code);
+CREATE INDEX ON redemption (user_id);
+CREATE INDEX ON redemption (order_id);
+CREATE INDEX ON redemption (created_at);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE "idempotency_key"
+(
+    id          SERIAL PRIMARY KEY,
+    key         VARCHAR(255) NOT NULL,
+    scope       VARCHAR(512) NOT NULL,
+    request     CHAR(64)     NOT NULL,
+    status_code INT          NOT NULL,
+    response    JSONB        NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    UNIQUE (scope, key)
+);
+
+
+CREATE INDEX ON idempotency_key (created_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "redemption" DROP COLUMN IF EXISTS reversed_at;
\ No newline at end of file
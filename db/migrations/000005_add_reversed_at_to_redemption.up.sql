@@ -0,0 +1 @@
+ALTER TABLE "redemption" ADD COLUMN reversed_at TIMESTAMPTZ;
\ No newline at end of file
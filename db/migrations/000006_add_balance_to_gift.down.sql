@@ -0,0 +1,2 @@
+ALTER TABLE "gift" DROP COLUMN IF EXISTS balance_mode,
+    DROP COLUMN IF EXISTS remaining_balance;
\ No newline at end of file
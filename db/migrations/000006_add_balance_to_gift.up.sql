@@ -0,0 +1,2 @@
+ALTER TABLE "gift" ADD COLUMN balance_mode BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN remaining_balance DECIMAL(20, 0) NOT NULL DEFAULT 0;
\ No newline at end of file
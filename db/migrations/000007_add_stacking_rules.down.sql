@@ -0,0 +1,7 @@
+ALTER TABLE "gift" DROP COLUMN IF EXISTS exclusive,
+    DROP COLUMN IF EXISTS stackable_with_gift,
+    DROP COLUMN IF EXISTS stackable_with_discount;
+
+ALTER TABLE "discount" DROP COLUMN IF EXISTS exclusive,
+    DROP COLUMN IF EXISTS stackable_with_gift,
+    DROP COLUMN IF EXISTS stackable_with_discount;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE "gift" ADD COLUMN exclusive BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN stackable_with_gift BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN stackable_with_discount BOOLEAN NOT NULL DEFAULT true;
+
+ALTER TABLE "discount" ADD COLUMN exclusive BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN stackable_with_gift BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN stackable_with_discount BOOLEAN NOT NULL DEFAULT false;
\ No newline at end of file
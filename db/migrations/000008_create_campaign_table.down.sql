@@ -0,0 +1,3 @@
+ALTER TABLE "discount" DROP COLUMN IF EXISTS campaign_id;
+ALTER TABLE "gift" DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS "campaign";
\ No newline at end of file
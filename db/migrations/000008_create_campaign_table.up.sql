@@ -0,0 +1,19 @@
+CREATE TABLE "campaign"
+(
+    id              SERIAL PRIMARY KEY,
+    name            VARCHAR(255)   NOT NULL,
+    start_date_time TIMESTAMPTZ,
+    expiration_date TIMESTAMPTZ,
+    budget          DECIMAL(20, 0) NOT NULL DEFAULT 0,
+    spent           DECIMAL(20, 0) NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "gift" ADD COLUMN campaign_id INT REFERENCES campaign (id) ON DELETE SET NULL;
+ALTER TABLE "discount" ADD COLUMN campaign_id INT REFERENCES campaign (id) ON DELETE SET NULL;
+
+
+CREATE INDEX ON campaign (name);
+CREATE INDEX ON gift (campaign_id);
+CREATE INDEX ON discount (campaign_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "discount" DROP COLUMN IF EXISTS job_id;
+ALTER TABLE "gift" DROP COLUMN IF EXISTS job_id;
+DROP TABLE IF EXISTS "job";
\ No newline at end of file
@@ -0,0 +1,22 @@
+CREATE TABLE "job"
+(
+    id          SERIAL PRIMARY KEY,
+    code_type   VARCHAR(16)  NOT NULL,
+    prefix      VARCHAR(255) NOT NULL DEFAULT '',
+    count       INT          NOT NULL,
+    generated   INT          NOT NULL DEFAULT 0,
+    status      VARCHAR(16)  NOT NULL,
+    error       TEXT         NOT NULL DEFAULT '',
+    template    JSONB        NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+ALTER TABLE "gift" ADD COLUMN job_id INT REFERENCES job (id) ON DELETE SET NULL;
+ALTER TABLE "discount" ADD COLUMN job_id INT REFERENCES job (id) ON DELETE SET NULL;
+
+
+CREATE INDEX ON job (status);
+CREATE INDEX ON gift (job_id);
+CREATE INDEX ON discount (job_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "discount" DROP COLUMN IF EXISTS conditions;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "discount" ADD COLUMN conditions JSONB NOT NULL DEFAULT '[]';
\ No newline at end of file
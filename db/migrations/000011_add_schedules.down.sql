@@ -0,0 +1,2 @@
+ALTER TABLE "gift" DROP COLUMN IF EXISTS schedule;
+ALTER TABLE "discount" DROP COLUMN IF EXISTS schedule;
\ No newline at end of file
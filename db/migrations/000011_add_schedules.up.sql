@@ -0,0 +1,2 @@
+ALTER TABLE "gift" ADD COLUMN schedule JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE "discount" ADD COLUMN schedule JSONB NOT NULL DEFAULT '[]';
\ No newline at end of file
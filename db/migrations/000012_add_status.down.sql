@@ -0,0 +1,2 @@
+ALTER TABLE "gift" DROP COLUMN IF EXISTS status;
+ALTER TABLE "discount" DROP COLUMN IF EXISTS status;
\ No newline at end of file
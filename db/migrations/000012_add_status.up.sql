@@ -0,0 +1,6 @@
+ALTER TABLE "gift" ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE "discount" ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+
+CREATE INDEX ON gift (status);
+CREATE INDEX ON discount (status);
\ No newline at end of file
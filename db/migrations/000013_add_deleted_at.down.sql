@@ -0,0 +1,2 @@
+ALTER TABLE "gift" DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE "discount" DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
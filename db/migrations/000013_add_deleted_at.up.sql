@@ -0,0 +1,6 @@
+ALTER TABLE "gift" ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE "discount" ADD COLUMN deleted_at TIMESTAMPTZ;
+
+
+CREATE INDEX ON gift (deleted_at);
+CREATE INDEX ON discount (deleted_at);
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE "audit_log"
+(
+    id         SERIAL PRIMARY KEY,
+    actor      VARCHAR(255) NOT NULL DEFAULT '',
+    action     VARCHAR(32)  NOT NULL,
+    entity     VARCHAR(32)  NOT NULL,
+    entity_id  VARCHAR(255) NOT NULL DEFAULT '',
+    before     JSONB,
+    after      JSONB,
+    trace_id   VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+
+CREATE INDEX ON audit_log (entity, entity_id);
+CREATE INDEX ON audit_log (actor);
+CREATE INDEX ON audit_log (trace_id);
+CREATE INDEX ON audit_log (created_at);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "discount" DROP COLUMN IF EXISTS version;
+ALTER TABLE "gift" DROP COLUMN IF EXISTS version;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "gift" ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE "discount" ADD COLUMN version INT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 var (
@@ -68,3 +70,23 @@ func Transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	}
 	return tx.Commit()
 }
+
+// ValuesPlaceholders returns the placeholders of a multi-row VALUES list, such as "($1,$2),($3,$4)" for two rows
+// of two columns.
+func ValuesPlaceholders(rows, cols int) string {
+	var b strings.Builder
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('(')
+		for j := 0; j < cols; j++ {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString("$" + strconv.Itoa(i*cols+j+1))
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
@@ -15,9 +15,135 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/gift": {
+        "/audit": {
+            "get": {
+                "description": "List the administrative changes to gifts, discounts, campaigns and jobs page by page, newest first.\nEach entry tells who made the change, taken from the X-Actor header, and holds the entity before\nand after it. The action is create, update, delete, restore, pause, resume, archive, bulk_create or\nimport, and the entity is gift, discount, campaign or job.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity code or ID",
+                        "name": "entityId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Trace ID of the request",
+                        "name": "traceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First date (2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last date (2006-01-02)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/audit.ListDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/campaign": {
+            "get": {
+                "description": "List campaigns page by page, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Campaign"
+                ],
+                "summary": "List campaigns",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/campaign.ListDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Initialize a new gift.",
+                "description": "Initialize a new campaign. Gifts and discounts join it through their campaignId.\nDates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business\ntimezone.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,17 +151,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "GiftDTO"
+                    "Campaign"
                 ],
-                "summary": "Initialize gift",
+                "summary": "Initialize campaign",
                 "parameters": [
                     {
-                        "description": "Gift init request",
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Campaign init request",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/gift.CreateRequest"
+                            "$ref": "#/definitions/campaign.CreateRequest"
                         }
                     }
                 ],
@@ -43,7 +175,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gift.DTO"
+                            "$ref": "#/definitions/campaign.DTO"
                         }
                     },
                     "400": {
@@ -61,9 +193,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/gift/use/{giftCode}": {
-            "post": {
-                "description": "Use a gift code.",
+        "/campaign/{campaignID}": {
+            "get": {
+                "description": "Get a campaign by ID, including how much of its budget has been spent.",
                 "consumes": [
                     "application/json"
                 ],
@@ -71,23 +203,153 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "GiftDTO"
+                    "Campaign"
                 ],
-                "summary": "Use gift",
+                "summary": "Get campaign",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "campaignID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/campaign.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update the name, date window and budget of a campaign.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Campaign"
+                ],
+                "summary": "Update campaign",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Gift code",
-                        "name": "giftCode",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "campaignID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Campaign",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/campaign.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/campaign.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a campaign. Its gifts and discounts are kept and no longer belong to any campaign.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Campaign"
+                ],
+                "summary": "Delete campaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "campaignID",
                         "in": "path",
                         "required": true
                     }
                 ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/checkout/apply": {
+            "post": {
+                "description": "Work out which of several gift and discount codes can be combined on an order, following each code's\nstacking rules, and calculate the final price without consuming any code. Codes that are left out\nare listed under rejected with the reason in their error field. The conditions of discounts are\nchecked against the cart.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checkout"
+                ],
+                "summary": "Apply codes",
+                "parameters": [
+                    {
+                        "description": "Apply request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/checkout.ApplyRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gift.DTO"
+                            "$ref": "#/definitions/handler.ApplyResponse"
                         }
                     },
                     "400": {
@@ -95,13 +357,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.Error"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
                     }
                 }
             }
         },
-        "/gift/{giftCode}": {
+        "/checkout/eligibility/{code}": {
             "get": {
-                "description": "Get a gift by code.",
+                "description": "Explain whether a gift or discount code can be used right now and, if not, why.\nThe reason is one of valid, unknown, not_started, expired, exhausted, paused, out_of_schedule,\nbelow_min_amount and ineligible; the error field carries the localized explanation.",
                 "consumes": [
                     "application/json"
                 ],
@@ -109,23 +377,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "GiftDTO"
+                    "Checkout"
                 ],
-                "summary": "Get gift",
+                "summary": "Check code eligibility",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Gift code",
-                        "name": "giftCode",
+                        "description": "Gift or discount code",
+                        "name": "code",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code type (gift or discount)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Order amount",
+                        "name": "amount",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gift.DTO"
+                            "$ref": "#/definitions/handler.EligibilityResponse"
                         }
                     },
                     "400": {
@@ -133,13 +413,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/handler.Error"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
                     }
                 }
             }
         },
-        "/health": {
-            "get": {
-                "description": "Health check",
+        "/checkout/quote": {
+            "post": {
+                "description": "Calculate the price of an order with a gift or discount code applied, without consuming the code.\nIf the code cannot be used, the reason is returned in the error field and the order amount is unchanged.\nThe conditions of a discount are checked against the cart; the ones not met are listed under\nfailedConditions.",
                 "consumes": [
                     "application/json"
                 ],
@@ -147,26 +433,2379 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "Checkout"
+                ],
+                "summary": "Quote code",
+                "parameters": [
+                    {
+                        "description": "Quote request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/checkout.QuoteRequest"
+                        }
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.QuoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "gift.CreateRequest": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "string"
-                },
-                "codePrefix": {
-                    "type": "string"
+        },
+        "/discount": {
+            "get": {
+                "description": "List discounts page by page, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "List discounts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.ListDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Initialize a new discount.\nDates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business\ntimezone.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Initialize discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Discount init request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/discount.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/archive/{discountCode}": {
+            "post": {
+                "description": "Retire a discount code for good, keeping its redemptions and history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Archive discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/pause/{discountCode}": {
+            "post": {
+                "description": "Stop a discount code from being used until it is resumed. It takes effect on the next use.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Pause discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/redeem/{discountCode}": {
+            "post": {
+                "description": "Apply a discount code to an order amount and consume one usage of it.\nRetries carrying the same Idempotency-Key header get the original response back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Redeem discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Redeem request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/discount.RedeemRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.PriceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/restore/{discountCode}": {
+            "post": {
+                "description": "Bring back a deleted discount that has not been purged yet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Restore discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/resume/{discountCode}": {
+            "post": {
+                "description": "Make a paused or draft discount code usable again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Resume discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/discount/{discountCode}": {
+            "get": {
+                "description": "Get a discount by code. The ETag header holds its version, to be sent back as If-Match on update.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Get discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the discount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a discount by code. The update only applies to the version in the If-Match header, or else\nin the version field, and fails with 412 when the discount has been changed since that version.\nThe used count is kept as it is.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Update discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Discount",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/discount.DTO"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the discount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a discount by code. It can be restored until it is purged after the retention period.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "DiscountDTO"
+                ],
+                "summary": "Delete discount",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Discount code",
+                        "name": "discountCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/discount": {
+            "get": {
+                "description": "Download the discounts created between two dates as CSV, streamed straight from the database.\nThe status is scheduled, active, expired, exhausted, draft, paused or archived.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export discounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First creation date (2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation date (2006-01-02)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/gift": {
+            "get": {
+                "description": "Download the gifts created between two dates as CSV, streamed straight from the database.\nThe status is scheduled, active, expired, exhausted, draft, paused or archived.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export gifts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First creation date (2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation date (2006-01-02)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/redemption": {
+            "get": {
+                "description": "Download the redemptions made between two dates as CSV, streamed straight from the database.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Export"
+                ],
+                "summary": "Export redemptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First redemption date (2006-01-02)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last redemption date (2006-01-02)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status (active or reversed)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code type (gift or discount)",
+                        "name": "type",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift": {
+            "get": {
+                "description": "List gifts page by page, newest first unless sorted otherwise, with the total number of matches.\nThe status is scheduled, active, expired, exhausted, draft, paused or archived. The sort is a comma\nseparated list of code, giftAmount, usedCount, startDateTime, expirationDate and createdAt, each\nin descending order when prefixed with a minus, such as -giftAmount,code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "List gifts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Code prefix",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Lowest gift amount",
+                        "name": "minAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Highest gift amount",
+                        "name": "maxAmount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First expiration date (2006-01-02)",
+                        "name": "expiresFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last expiration date (2006-01-02)",
+                        "name": "expiresTo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Campaign ID",
+                        "name": "campaignId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort fields",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.ListDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Initialize a new gift.\nDates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business\ntimezone.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Initialize gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Gift init request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gift.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/archive/{giftCode}": {
+            "post": {
+                "description": "Retire a gift code for good, keeping its redemptions and history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Archive gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/balance/{giftCode}": {
+            "get": {
+                "description": "Get the remaining balance of a balance mode gift.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Get gift balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.BalanceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/debit/{giftCode}": {
+            "post": {
+                "description": "Spend an amount from the balance of a balance mode gift. Retries carrying the same Idempotency-Key\nheader get the original response back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Debit gift balance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Debit request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gift.DebitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.BalanceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/pause/{giftCode}": {
+            "post": {
+                "description": "Stop a gift code from being used until it is resumed. It takes effect on the next use.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Pause gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/reservation/{reservationID}/cancel": {
+            "post": {
+                "description": "Release the gift usage held by a reservation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Cancel gift reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Reservation ID",
+                        "name": "reservationID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.ReservationDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/reservation/{reservationID}/confirm": {
+            "post": {
+                "description": "Turn the gift usage held by a reservation into a redemption.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Confirm gift reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Reservation ID",
+                        "name": "reservationID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/reserve/{giftCode}": {
+            "post": {
+                "description": "Hold one usage of a gift code for a checkout. The hold counts toward the usage limit until it is\nconfirmed, cancelled or expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Reserve gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Reserve request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gift.ReserveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.ReservationDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/restore/{giftCode}": {
+            "post": {
+                "description": "Bring back a deleted gift that has not been purged yet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Restore gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/resume/{giftCode}": {
+            "post": {
+                "description": "Make a paused or draft gift code usable again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Resume gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/use/{giftCode}": {
+            "post": {
+                "description": "Use a gift code. Retries carrying the same Idempotency-Key header get the original response back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Use gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Use request",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/gift.UseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/gift/{giftCode}": {
+            "get": {
+                "description": "Get a gift by code. The ETag header holds its version, to be sent back as If-Match on update.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Get gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the gift"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a gift by code. The update only applies to the version in the If-Match header, or else in\nthe version field, and fails with 412 when the gift has been changed since that version.\nThe used count and remaining balance are kept as they are.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Update gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Gift",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gift.DTO"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the gift"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a gift by code. It can be restored until it is purged after the retention period.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "GiftDTO"
+                ],
+                "summary": "Delete gift",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Gift code",
+                        "name": "giftCode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Health check",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/import/{type}": {
+            "post": {
+                "description": "Import existing gift or discount codes, keeping their used counts, from a CSV file with a header row\nor a JSON Lines file sent as the request body. CSV columns and JSON fields are named like the fields\nof the create request, plus usedCount (and remainingBalance for gifts). Nothing is written unless\nevery line is valid; the lines that are not are listed under errors. With dryRun the file is only\nchecked.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Import"
+                ],
+                "summary": "Import codes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code type (gift or discount)",
+                        "name": "type",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File format (csv or jsonl), taken from the content type when empty",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only check the file",
+                        "name": "dryRun",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/job": {
+            "post": {
+                "description": "Generate count gift or discount codes from a template in the background. The job starts as pending;\nfollow its progress with GET /job/{jobID} and download the codes once it has completed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "Create bulk generation job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Who makes the change, for the audit log",
+                        "name": "X-Actor",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Job request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/job.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/job.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/job/{jobID}": {
+            "get": {
+                "description": "Get the status and progress of a bulk generation job. A job that makes no progress for 10 minutes,\nsuch as one interrupted by a restart, is marked failed; the codes it generated are kept.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "Get bulk generation job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "jobID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/job.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/job/{jobID}/download": {
+            "get": {
+                "description": "Download the codes generated by a completed job as CSV.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Job"
+                ],
+                "summary": "Download bulk generation job",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Job ID",
+                        "name": "jobID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/redemption/code/{code}": {
+            "get": {
+                "description": "List the redemptions of a gift or discount code page by page, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redemption"
+                ],
+                "summary": "List redemptions of a code",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gift or discount code",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/redemption.ListDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/redemption/order/{orderID}/reverse": {
+            "post": {
+                "description": "Restore the usages consumed by every redemption of an order that has not been reversed yet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redemption"
+                ],
+                "summary": "Reverse order redemptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "orderID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/redemption.DTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/redemption/reverse/{redemptionID}": {
+            "post": {
+                "description": "Restore the usage consumed by a redemption, for example when its order is refunded.\nA redemption can only be reversed once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redemption"
+                ],
+                "summary": "Reverse redemption",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Redemption ID",
+                        "name": "redemptionID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/redemption.DTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/redemption/user/{userID}": {
+            "get": {
+                "description": "List the redemptions made by a user page by page, newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Redemption"
+                ],
+                "summary": "List redemptions of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/redemption.ListDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/handler.Error"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.DTO": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entityId": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "traceId": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.ListDTO": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.DTO"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "campaign.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "type": "integer"
+                },
+                "expirationDate": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "startDateTime": {
+                    "type": "string"
+                }
+            }
+        },
+        "campaign.DTO": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expirationDate": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "spent": {
+                    "type": "integer"
+                },
+                "startDateTime": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "campaign.ListDTO": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/campaign.DTO"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "checkout.AppliedCodeDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "checkout.ApplyRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "cart": {
+                    "type": "object"
+                },
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "checkout.QuoteRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "cart": {
+                    "type": "object"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "checkout.Reason": {
+            "type": "string",
+            "enum": [
+                "valid",
+                "unknown",
+                "not_started",
+                "expired",
+                "exhausted",
+                "paused",
+                "out_of_schedule",
+                "below_min_amount",
+                "ineligible"
+            ],
+            "x-enum-varnames": [
+                "ReasonValid",
+                "ReasonUnknown",
+                "ReasonNotStarted",
+                "ReasonExpired",
+                "ReasonExhausted",
+                "ReasonPaused",
+                "ReasonOutOfSchedule",
+                "ReasonBelowMinAmount",
+                "ReasonIneligible"
+            ]
+        },
+        "checkout.RejectedCodeDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "failedConditions": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "discount.Cart": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/discount.CartItem"
+                    }
+                },
+                "segments": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "discount.CartItem": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "sku": {
+                    "type": "string"
+                }
+            }
+        },
+        "discount.Condition": {
+            "type": "object",
+            "properties": {
+                "operator": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "values": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "discount.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "campaignId": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "codePrefix": {
+                    "type": "string"
+                },
+                "conditions": {
+                    "description": "Conditions restrict the discount to carts that meet all of them, for example to some product categories.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/discount.Condition"
+                    }
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "exclusive": {
+                    "description": "Exclusive discounts cannot be combined with any other code. A discount can be combined with gifts but not\nwith other discounts unless StackableWithGift or StackableWithDiscount says otherwise.",
+                    "type": "boolean"
+                },
+                "expirationDate": {
+                    "type": "string"
+                },
+                "maxAmount": {
+                    "type": "integer"
+                },
+                "minAmount": {
+                    "type": "integer"
+                },
+                "pattern": {
+                    "description": "Pattern generates the code from a template such as \"NOWRUZ-####-??\", where # is a digit and ? a letter.\nIt replaces CodePrefix and cannot be combined with Code.",
+                    "type": "string"
+                },
+                "percentOff": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "description": "Schedule restricts the discount to recurring time windows, such as weekends or happy hours, within its\nvalidity.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schedule.Window"
+                    }
+                },
+                "stackableWithDiscount": {
+                    "type": "boolean"
+                },
+                "stackableWithGift": {
+                    "type": "boolean"
+                },
+                "startDateTime": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is active, the default, or draft for a discount that cannot be used until it is resumed.",
+                    "type": "string"
+                },
+                "usageLimit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "discount.DTO": {
+            "type": "object",
+            "properties": {
+                "campaignId": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "conditions": {
+                    "description": "Conditions restrict the discount to carts that meet all of them.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/discount.Condition"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "exclusive": {
+                    "type": "boolean"
+                },
+                "expirationDate": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "maxAmount": {
+                    "type": "integer"
+                },
+                "minAmount": {
+                    "type": "integer"
+                },
+                "percentOff": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "description": "Schedule restricts the discount to recurring time windows within its validity.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schedule.Window"
+                    }
+                },
+                "stackableWithDiscount": {
+                    "type": "boolean"
+                },
+                "stackableWithGift": {
+                    "type": "boolean"
+                },
+                "startDateTime": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is draft, active, paused or archived. It is changed through pause, resume and archive, not by updates.",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "usageLimit": {
+                    "type": "integer"
+                },
+                "usedCount": {
+                    "type": "integer"
+                },
+                "version": {
+                    "description": "Version is raised by every update and status change and is sent as the ETag. An update applies to the\nversion given by the If-Match header, or else by this field. Updates leave the used count as it is.",
+                    "type": "integer"
+                }
+            }
+        },
+        "discount.ListDTO": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/discount.DTO"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "discount.PriceDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "finalAmount": {
+                    "type": "integer"
+                },
+                "orderAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "discount.RedeemRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "cart": {
+                    "$ref": "#/definitions/discount.Cart"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "gift.BalanceDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "debited": {
+                    "type": "integer"
+                },
+                "giftAmount": {
+                    "type": "integer"
+                },
+                "remainingBalance": {
+                    "type": "integer"
+                }
+            }
+        },
+        "gift.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "balanceMode": {
+                    "description": "BalanceMode creates a stored-value gift whose GiftAmount is spent over several debits.",
+                    "type": "boolean"
+                },
+                "campaignId": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "codePrefix": {
+                    "type": "string"
+                },
+                "exclusive": {
+                    "description": "Exclusive gifts cannot be combined with any other code. A gift can be combined with other gifts and\nwith discounts unless StackableWithGift or StackableWithDiscount is set to false.",
+                    "type": "boolean"
                 },
                 "expirationDate": {
                     "type": "string"
@@ -174,9 +2813,30 @@ const docTemplate = `{
                 "giftAmount": {
                     "type": "integer"
                 },
+                "pattern": {
+                    "description": "Pattern generates the code from a template such as \"NOWRUZ-####-??\", where # is a digit and ? a letter.\nIt replaces CodePrefix and cannot be combined with Code.",
+                    "type": "string"
+                },
+                "schedule": {
+                    "description": "Schedule restricts the gift to recurring time windows, such as weekends or happy hours, within its validity.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schedule.Window"
+                    }
+                },
+                "stackableWithDiscount": {
+                    "type": "boolean"
+                },
+                "stackableWithGift": {
+                    "type": "boolean"
+                },
                 "startDateTime": {
                     "type": "string"
                 },
+                "status": {
+                    "description": "Status is active, the default, or draft for a gift that cannot be used until it is resumed.",
+                    "type": "string"
+                },
                 "usageLimit": {
                     "type": "integer"
                 }
@@ -185,12 +2845,21 @@ const docTemplate = `{
         "gift.DTO": {
             "type": "object",
             "properties": {
+                "balanceMode": {
+                    "type": "boolean"
+                },
+                "campaignId": {
+                    "type": "integer"
+                },
                 "code": {
                     "type": "string"
                 },
                 "createdAt": {
                     "type": "string"
                 },
+                "exclusive": {
+                    "type": "boolean"
+                },
                 "expirationDate": {
                     "type": "string"
                 },
@@ -200,9 +2869,29 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "remainingBalance": {
+                    "type": "integer"
+                },
+                "schedule": {
+                    "description": "Schedule restricts the gift to recurring time windows within its validity.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schedule.Window"
+                    }
+                },
+                "stackableWithDiscount": {
+                    "type": "boolean"
+                },
+                "stackableWithGift": {
+                    "type": "boolean"
+                },
                 "startDateTime": {
                     "type": "string"
                 },
+                "status": {
+                    "description": "Status is draft, active, paused or archived. It is changed through pause, resume and archive, not by updates.",
+                    "type": "string"
+                },
                 "updatedAt": {
                     "type": "string"
                 },
@@ -211,6 +2900,144 @@ const docTemplate = `{
                 },
                 "usedCount": {
                     "type": "integer"
+                },
+                "version": {
+                    "description": "Version is raised by every update and status change and is sent as the ETag. An update applies to the\nversion given by the If-Match header, or else by this field. Updates leave the used count and remaining\nbalance as they are.",
+                    "type": "integer"
+                }
+            }
+        },
+        "gift.DebitRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "gift.ListDTO": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/gift.DTO"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "gift.ReservationDTO": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "gift.ReserveRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "ttlSeconds": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "gift.UseRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.ApplyResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/checkout.AppliedCodeDTO"
+                    }
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "finalAmount": {
+                    "type": "integer"
+                },
+                "orderAmount": {
+                    "type": "integer"
+                },
+                "rejected": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.RejectedCodeResponse"
+                    }
+                }
+            }
+        },
+        "handler.EligibilityResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "eligible": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "$ref": "#/definitions/handler.Error"
+                },
+                "reason": {
+                    "$ref": "#/definitions/checkout.Reason"
+                },
+                "type": {
+                    "type": "string"
                 }
             }
         },
@@ -228,6 +3055,222 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.ImportLineErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "$ref": "#/definitions/handler.Error"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handler.ImportLineErrorResponse"
+                    }
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "records": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.QuoteResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "discountAmount": {
+                    "type": "integer"
+                },
+                "error": {
+                    "$ref": "#/definitions/handler.Error"
+                },
+                "failedConditions": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "finalAmount": {
+                    "type": "integer"
+                },
+                "orderAmount": {
+                    "type": "integer"
+                },
+                "remainingUsages": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.RejectedCodeResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "$ref": "#/definitions/handler.Error"
+                },
+                "failedConditions": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "importer.LineErrorDTO": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "job.CreateRequest": {
+            "type": "object"
+        },
+        "job.DTO": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "finishedAt": {
+                    "type": "string"
+                },
+                "generated": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "progress": {
+                    "type": "number"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "redemption.DTO": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "codeType": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "orderId": {
+                    "type": "string"
+                },
+                "reversedAt": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "redemption.ListDTO": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/redemption.DTO"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "pageSize": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "schedule.Window": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
         "serr.ErrorCode": {
             "type": "string",
             "enum": [
@@ -237,7 +3280,62 @@ const docTemplate = `{
                 "INVALID_DISCOUNT_ID",
                 "INVALID_DISCOUNT_CODE",
                 "PERMISSION",
-                "GIFT_USAGE_LIMIT_REACHED"
+                "GIFT_USAGE_LIMIT_REACHED",
+                "GIFT_NOT_STARTED",
+                "GIFT_EXPIRED",
+                "INVALID_AMOUNT",
+                "INVALID_CODE_TYPE",
+                "UNKNOWN_CODE",
+                "DISCOUNT_NOT_STARTED",
+                "DISCOUNT_EXPIRED",
+                "DISCOUNT_USAGE_LIMIT_REACHED",
+                "DISCOUNT_MIN_AMOUNT_NOT_REACHED",
+                "INVALID_IDEMPOTENCY_KEY",
+                "IDEMPOTENCY_KEY_IN_PROGRESS",
+                "IDEMPOTENCY_KEY_REUSED",
+                "RESERVATION_NOT_FOUND",
+                "INVALID_RESERVATION_TTL",
+                "REDEMPTION_NOT_FOUND",
+                "REDEMPTION_ALREADY_REVERSED",
+                "NO_USAGE_TO_RESTORE",
+                "GIFT_BALANCE_MODE",
+                "GIFT_NOT_BALANCE_MODE",
+                "GIFT_BALANCE_USED_UP",
+                "INSUFFICIENT_GIFT_BALANCE",
+                "DUPLICATE_CODE",
+                "CODE_NOT_STACKABLE",
+                "ORDER_AMOUNT_COVERED",
+                "CAMPAIGN_NOT_FOUND",
+                "CAMPAIGN_NOT_STARTED",
+                "CAMPAIGN_ENDED",
+                "CAMPAIGN_BUDGET_USED_UP",
+                "INVALID_CAMPAIGN",
+                "JOB_NOT_FOUND",
+                "JOB_NOT_FINISHED",
+                "INVALID_JOB",
+                "INVALID_CHECK_CHARACTER",
+                "CODE_GENERATION_FAILED",
+                "INVALID_PATTERN",
+                "PATTERN_EXHAUSTED",
+                "INVALID_IMPORT",
+                "INVALID_IMPORT_RECORD",
+                "CODE_ALREADY_EXISTS",
+                "INVALID_FILTER",
+                "INVALID_CONDITION",
+                "DISCOUNT_CONDITIONS_NOT_MET",
+                "INVALID_SCHEDULE",
+                "GIFT_OUT_OF_SCHEDULE",
+                "DISCOUNT_OUT_OF_SCHEDULE",
+                "INVALID_DATE",
+                "INVALID_STATUS",
+                "INVALID_STATUS_TRANSITION",
+                "GIFT_PAUSED",
+                "GIFT_UNAVAILABLE",
+                "DISCOUNT_PAUSED",
+                "DISCOUNT_UNAVAILABLE",
+                "DELETED_CODE_NOT_FOUND",
+                "INVALID_VERSION",
+                "VERSION_MISMATCH"
             ],
             "x-enum-varnames": [
                 "ErrInternal",
@@ -246,7 +3344,62 @@ const docTemplate = `{
                 "ErrInvalidDiscountID",
                 "ErrInvalidDiscountCode",
                 "ErrPermission",
-                "ErrGiftUsageLimitReached"
+                "ErrGiftUsageLimitReached",
+                "ErrGiftNotStarted",
+                "ErrGiftExpired",
+                "ErrInvalidAmount",
+                "ErrInvalidCodeType",
+                "ErrUnknownCode",
+                "ErrDiscountNotStarted",
+                "ErrDiscountExpired",
+                "ErrDiscountUsageLimitReached",
+                "ErrDiscountMinAmountNotReached",
+                "ErrInvalidIdempotencyKey",
+                "ErrIdempotencyKeyInProgress",
+                "ErrIdempotencyKeyReused",
+                "ErrReservationNotFound",
+                "ErrInvalidReservationTTL",
+                "ErrRedemptionNotFound",
+                "ErrRedemptionAlreadyReversed",
+                "ErrNoUsageToRestore",
+                "ErrGiftBalanceMode",
+                "ErrGiftNotBalanceMode",
+                "ErrGiftBalanceUsedUp",
+                "ErrInsufficientGiftBalance",
+                "ErrDuplicateCode",
+                "ErrCodeNotStackable",
+                "ErrOrderAmountCovered",
+                "ErrCampaignNotFound",
+                "ErrCampaignNotStarted",
+                "ErrCampaignEnded",
+                "ErrCampaignBudgetUsedUp",
+                "ErrInvalidCampaign",
+                "ErrJobNotFound",
+                "ErrJobNotFinished",
+                "ErrInvalidJob",
+                "ErrInvalidCheckCharacter",
+                "ErrCodeGenerationFailed",
+                "ErrInvalidPattern",
+                "ErrPatternExhausted",
+                "ErrInvalidImport",
+                "ErrInvalidImportRecord",
+                "ErrCodeAlreadyExists",
+                "ErrInvalidFilter",
+                "ErrInvalidCondition",
+                "ErrDiscountConditionsNotMet",
+                "ErrInvalidSchedule",
+                "ErrGiftOutOfSchedule",
+                "ErrDiscountOutOfSchedule",
+                "ErrInvalidDate",
+                "ErrInvalidStatus",
+                "ErrInvalidStatusTransition",
+                "ErrGiftPaused",
+                "ErrGiftUnavailable",
+                "ErrDiscountPaused",
+                "ErrDiscountUnavailable",
+                "ErrDeletedCodeNotFound",
+                "ErrInvalidVersion",
+                "ErrVersionMismatch"
             ]
         }
     }
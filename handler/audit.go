@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/audit"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AuditHandler struct {
+	audit *audit.Service
+}
+
+func NewAuditHandler(audit *audit.Service) AuditHandler {
+	return AuditHandler{
+		audit: audit,
+	}
+}
+
+func SetupAuditRoutes(s *server.Server, h AuditHandler) {
+	g := s.Engine.Group("/audit")
+	g.GET("", h.ListAudit)
+}
+
+// ListAudit godoc
+// @Summary      List audit log
+// @Description  List the administrative changes to gifts, discounts, campaigns and jobs page by page, newest first.
+// @Description  Each entry tells who made the change, taken from the X-Actor header, and holds the entity before
+// @Description  and after it. The action is create, update, delete, restore, pause, resume, archive, bulk_create or
+// @Description  import, and the entity is gift, discount, campaign or job.
+// @Tags         Audit
+// @Accept       json
+// @Produce      json
+// @Param        actor			query		string				false	"Actor"
+// @Param        action			query		string				false	"Action"
+// @Param        entity			query		string				false	"Entity"
+// @Param        entityId		query		string				false	"Entity code or ID"
+// @Param        traceId		query		string				false	"Trace ID of the request"
+// @Param        from			query		string				false	"First date (2006-01-02)"
+// @Param        to				query		string				false	"Last date (2006-01-02)"
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	audit.ListDTO
+// @Failure      400  			{object}	Error
+// @Router       	/audit		[get]
+func (h AuditHandler) ListAudit(ctx *gin.Context) {
+	var req audit.ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	page, pageSize := getPaginationParams(ctx)
+
+	result, err := h.audit.List(&req, page, pageSize)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
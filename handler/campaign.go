@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/campaign"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type CampaignHandler struct {
+	campaign *campaign.Service
+}
+
+func NewCampaignHandler(campaign *campaign.Service) CampaignHandler {
+	return CampaignHandler{
+		campaign: campaign,
+	}
+}
+
+func SetupCampaignRoutes(s *server.Server, h CampaignHandler) {
+	g := s.Engine.Group("/campaign")
+	g.POST("", h.InitCampaign)
+	g.GET("", h.ListCampaigns)
+	g.GET("/:campaignID", h.GetCampaign)
+	g.PUT("/:campaignID", h.UpdateCampaign)
+	g.DELETE("/:campaignID", h.DeleteCampaign)
+}
+
+// InitCampaign godoc
+// @Summary			Initialize campaign
+// @Description		Initialize a new campaign. Gifts and discounts join it through their campaignId.
+// @Description		Dates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business
+// @Description		timezone.
+// @Tags			Campaign
+// @Accept			json
+// @Produce      	json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        body			body		campaign.CreateRequest		true	"Campaign init request"
+// @Success      200			{object}	campaign.DTO
+// @Failure      	400  			{object}	Error
+// @Failure      	500  			{object}  	Error
+// @Router       	/campaign		[post]
+func (h CampaignHandler) InitCampaign(ctx *gin.Context) {
+	var req campaign.CreateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	result, err := h.campaign.Create(getActor(ctx), &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListCampaigns godoc
+// @Summary      List campaigns
+// @Description  List campaigns page by page, newest first.
+// @Tags         Campaign
+// @Accept       json
+// @Produce      json
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	campaign.ListDTO
+// @Failure      500  			{object}	Error
+// @Router       	/campaign		[get]
+func (h CampaignHandler) ListCampaigns(ctx *gin.Context) {
+	page, pageSize := getPaginationParams(ctx)
+	result, err := h.campaign.List(page, pageSize)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetCampaign godoc
+// @Summary      Get campaign
+// @Description  Get a campaign by ID, including how much of its budget has been spent.
+// @Tags         Campaign
+// @Accept       json
+// @Produce      json
+// @Param        campaignID		path		int					true	"Campaign ID"
+// @Success      200			{object}	campaign.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/campaign/{campaignID}		[get]
+func (h CampaignHandler) GetCampaign(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("campaignID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.campaign.GetByID(id)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UpdateCampaign godoc
+// @Summary      Update campaign
+// @Description  Update the name, date window and budget of a campaign.
+// @Tags         Campaign
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        campaignID		path		int						true	"Campaign ID"
+// @Param        body			body		campaign.CreateRequest	true	"Campaign"
+// @Success      200			{object}	campaign.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/campaign/{campaignID}		[put]
+func (h CampaignHandler) UpdateCampaign(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("campaignID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req campaign.CreateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	result, err := h.campaign.Update(getActor(ctx), id, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeleteCampaign godoc
+// @Summary      Delete campaign
+// @Description  Delete a campaign. Its gifts and discounts are kept and no longer belong to any campaign.
+// @Tags         Campaign
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        campaignID		path		int					true	"Campaign ID"
+// @Success      204
+// @Failure      400  			{object}	Error
+// @Router       	/campaign/{campaignID}		[delete]
+func (h CampaignHandler) DeleteCampaign(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("campaignID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	err = h.campaign.Delete(getActor(ctx), id)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/checkout"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type CheckoutHandler struct {
+	checkout *checkout.Service
+}
+
+func NewCheckoutHandler(checkout *checkout.Service) CheckoutHandler {
+	return CheckoutHandler{
+		checkout: checkout,
+	}
+}
+
+func SetupCheckoutRoutes(s *server.Server, h CheckoutHandler) {
+	g := s.Engine.Group("/checkout")
+	g.POST("/quote", h.Quote)
+	g.POST("/apply", h.Apply)
+	g.GET("/eligibility/:code", h.Eligibility)
+}
+
+type QuoteResponse struct {
+	*checkout.QuoteDTO
+	Error *Error `json:"error"`
+}
+
+type EligibilityResponse struct {
+	*checkout.EligibilityDTO
+	Error *Error `json:"error"`
+}
+
+type ApplyResponse struct {
+	*checkout.ApplyDTO
+	Rejected []RejectedCodeResponse `json:"rejected"`
+}
+
+type RejectedCodeResponse struct {
+	*checkout.RejectedCodeDTO
+	Error *Error `json:"error"`
+}
+
+// Quote godoc
+// @Summary      Quote code
+// @Description  Calculate the price of an order with a gift or discount code applied, without consuming the code.
+// @Description  If the code cannot be used, the reason is returned in the error field and the order amount is unchanged.
+// @Description  The conditions of a discount are checked against the cart; the ones not met are listed under
+// @Description  failedConditions.
+// @Tags         Checkout
+// @Accept       json
+// @Produce      json
+// @Param        body			body		checkout.QuoteRequest	true	"Quote request"
+// @Success      200			{object}	QuoteResponse
+// @Failure      400  			{object}	Error
+// @Failure      500  			{object}	Error
+// @Router       	/checkout/quote		[post]
+func (h CheckoutHandler) Quote(ctx *gin.Context) {
+	var req checkout.QuoteRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	result, err := h.checkout.Quote(&req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	resp := QuoteResponse{QuoteDTO: result}
+	if result.Failure != nil {
+		resp.Error = localizeError(ctx, result.Failure)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Apply godoc
+// @Summary      Apply codes
+// @Description  Work out which of several gift and discount codes can be combined on an order, following each code's
+// @Description  stacking rules, and calculate the final price without consuming any code. Codes that are left out
+// @Description  are listed under rejected with the reason in their error field. The conditions of discounts are
+// @Description  checked against the cart.
+// @Tags         Checkout
+// @Accept       json
+// @Produce      json
+// @Param        body			body		checkout.ApplyRequest	true	"Apply request"
+// @Success      200			{object}	ApplyResponse
+// @Failure      400  			{object}	Error
+// @Failure      500  			{object}	Error
+// @Router       	/checkout/apply		[post]
+func (h CheckoutHandler) Apply(ctx *gin.Context) {
+	var req checkout.ApplyRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	result, err := h.checkout.Apply(&req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	resp := ApplyResponse{ApplyDTO: result, Rejected: make([]RejectedCodeResponse, 0, len(result.Rejected))}
+	for _, r := range result.Rejected {
+		resp.Rejected = append(resp.Rejected, RejectedCodeResponse{RejectedCodeDTO: r, Error: localizeError(ctx, r.Failure)})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// Eligibility godoc
+// @Summary      Check code eligibility
+// @Description  Explain whether a gift or discount code can be used right now and, if not, why.
+// @Description  The reason is one of valid, unknown, not_started, expired, exhausted, paused, out_of_schedule,
+// @Description  below_min_amount and ineligible; the error field carries the localized explanation.
+// @Tags         Checkout
+// @Accept       json
+// @Produce      json
+// @Param        code			path		string				true	"Gift or discount code"
+// @Param        type			query		string				false	"Code type (gift or discount)"
+// @Param        amount			query		int					false	"Order amount"
+// @Success      200			{object}	EligibilityResponse
+// @Failure      400  			{object}	Error
+// @Failure      500  			{object}	Error
+// @Router       	/checkout/eligibility/{code}		[get]
+func (h CheckoutHandler) Eligibility(ctx *gin.Context) {
+	var req checkout.EligibilityRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	req.Code = ctx.Param("code")
+	result, err := h.checkout.Eligibility(&req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	resp := EligibilityResponse{EligibilityDTO: result}
+	if result.Failure != nil {
+		resp.Error = localizeError(ctx, result.Failure)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
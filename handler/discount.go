@@ -0,0 +1,323 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/discount"
+	"discount/service/idempotency"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type DiscountHandler struct {
+	discount    *discount.Service
+	idempotency *idempotency.Service
+}
+
+func NewDiscountHandler(discount *discount.Service, idempotency *idempotency.Service) DiscountHandler {
+	return DiscountHandler{
+		discount:    discount,
+		idempotency: idempotency,
+	}
+}
+
+func SetupDiscountRoutes(s *server.Server, h DiscountHandler) {
+	g := s.Engine.Group("/discount")
+	g.POST("", h.InitDiscount)
+	g.GET("", h.ListDiscounts)
+	g.GET("/:discountCode", h.GetDiscount)
+	g.PUT("/:discountCode", h.UpdateDiscount)
+	g.DELETE("/:discountCode", h.DeleteDiscount)
+	g.POST("/restore/:discountCode", h.RestoreDiscount)
+	g.POST("/redeem/:discountCode", idempotent(h.idempotency), h.RedeemDiscount)
+	g.POST("/pause/:discountCode", h.PauseDiscount)
+	g.POST("/resume/:discountCode", h.ResumeDiscount)
+	g.POST("/archive/:discountCode", h.ArchiveDiscount)
+}
+
+// InitDiscount godoc
+// @Summary			Initialize discount
+// @Description		Initialize a new discount.
+// @Description		Dates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business
+// @Description		timezone.
+// @Tags			DiscountDTO
+// @Accept			json
+// @Produce      	json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        body			body		discount.CreateRequest		true	"Discount init request"
+// @Success      200			{object}	discount.DTO
+// @Failure      	400  			{object}	Error
+// @Failure      	500  			{object}  	Error
+// @Router       	/discount		[post]
+func (h DiscountHandler) InitDiscount(ctx *gin.Context) {
+	var req discount.CreateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	result, err := h.discount.Create(getActor(ctx), &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListDiscounts godoc
+// @Summary      List discounts
+// @Description  List discounts page by page, newest first.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	discount.ListDTO
+// @Failure      500  			{object}	Error
+// @Router       	/discount		[get]
+func (h DiscountHandler) ListDiscounts(ctx *gin.Context) {
+	page, pageSize := getPaginationParams(ctx)
+	result, err := h.discount.List(page, pageSize)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetDiscount godoc
+// @Summary      Get discount
+// @Description  Get a discount by code. The ETag header holds its version, to be sent back as If-Match on update.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      200			{object}	discount.DTO
+// @Header       200			{string}	ETag				"Version of the discount"
+// @Failure      400  			{object}	Error
+// @Router       	/discount/{discountCode}		[get]
+func (h DiscountHandler) GetDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.discount.GetByCode(discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	setETag(ctx, result.Version)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UpdateDiscount godoc
+// @Summary      Update discount
+// @Description  Update a discount by code. The update only applies to the version in the If-Match header, or else
+// @Description  in the version field, and fails with 412 when the discount has been changed since that version.
+// @Description  The used count is kept as it is.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        If-Match		header		string				false	"ETag of the version being updated"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Param        body			body		discount.DTO		true	"Discount"
+// @Success      200			{object}	discount.DTO
+// @Header       200			{string}	ETag				"Version of the discount"
+// @Failure      400  			{object}	Error
+// @Failure      412  			{object}	Error
+// @Router       	/discount/{discountCode}		[put]
+func (h DiscountHandler) UpdateDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req discount.DTO
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	version, err := ifMatch(ctx, req.Version)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	req.Version = version
+
+	result, err := h.discount.UpdateByCode(getActor(ctx), discountCode, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	setETag(ctx, result.Version)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeleteDiscount godoc
+// @Summary      Delete discount
+// @Description  Delete a discount by code. It can be restored until it is purged after the retention period.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      204
+// @Failure      400  			{object}	Error
+// @Router       	/discount/{discountCode}		[delete]
+func (h DiscountHandler) DeleteDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	err := h.discount.DeleteByCode(getActor(ctx), discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// RestoreDiscount godoc
+// @Summary      Restore discount
+// @Description  Bring back a deleted discount that has not been purged yet.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      200			{object}	discount.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/discount/restore/{discountCode}		[post]
+func (h DiscountHandler) RestoreDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.discount.Restore(getActor(ctx), discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RedeemDiscount godoc
+// @Summary      Redeem discount
+// @Description  Apply a discount code to an order amount and consume one usage of it.
+// @Description  Retries carrying the same Idempotency-Key header get the original response back.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        discountCode	path		string					true	"Discount code"
+// @Param        Idempotency-Key	header		string					false	"Idempotency key"
+// @Param        body			body		discount.RedeemRequest	true	"Redeem request"
+// @Success      200			{object}	discount.PriceDTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/discount/redeem/{discountCode}		[post]
+func (h DiscountHandler) RedeemDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req discount.RedeemRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	result, err := h.discount.Redeem(discountCode, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// PauseDiscount godoc
+// @Summary      Pause discount
+// @Description  Stop a discount code from being used until it is resumed. It takes effect on the next use.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      200			{object}	discount.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/discount/pause/{discountCode}		[post]
+func (h DiscountHandler) PauseDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.discount.Pause(getActor(ctx), discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ResumeDiscount godoc
+// @Summary      Resume discount
+// @Description  Make a paused or draft discount code usable again.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      200			{object}	discount.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/discount/resume/{discountCode}		[post]
+func (h DiscountHandler) ResumeDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.discount.Resume(getActor(ctx), discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ArchiveDiscount godoc
+// @Summary      Archive discount
+// @Description  Retire a discount code for good, keeping its redemptions and history.
+// @Tags         DiscountDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        discountCode	path		string				true	"Discount code"
+// @Success      200			{object}	discount.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/discount/archive/{discountCode}		[post]
+func (h DiscountHandler) ArchiveDiscount(ctx *gin.Context) {
+	discountCode := ctx.Param("discountCode")
+	if discountCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.discount.Archive(getActor(ctx), discountCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/export"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"io"
+)
+
+type ExportHandler struct {
+	export *export.Service
+}
+
+func NewExportHandler(export *export.Service) ExportHandler {
+	return ExportHandler{
+		export: export,
+	}
+}
+
+func SetupExportRoutes(s *server.Server, h ExportHandler) {
+	g := s.Engine.Group("/export")
+	g.GET("/gift", h.ExportGifts)
+	g.GET("/discount", h.ExportDiscounts)
+	g.GET("/redemption", h.ExportRedemptions)
+}
+
+// ExportGifts godoc
+// @Summary      Export gifts
+// @Description  Download the gifts created between two dates as CSV, streamed straight from the database.
+// @Description  The status is scheduled, active, expired, exhausted, draft, paused or archived.
+// @Tags         Export
+// @Produce      text/csv
+// @Param        from			query		string				false	"First creation date (2006-01-02)"
+// @Param        to				query		string				false	"Last creation date (2006-01-02)"
+// @Param        prefix			query		string				false	"Code prefix"
+// @Param        status			query		string				false	"Status"
+// @Success      200			{file}		file
+// @Failure      400  			{object}	Error
+// @Router       	/export/gift		[get]
+func (h ExportHandler) ExportGifts(ctx *gin.Context) {
+	h.write(ctx, "gifts.csv", h.export.WriteGifts)
+}
+
+// ExportDiscounts godoc
+// @Summary      Export discounts
+// @Description  Download the discounts created between two dates as CSV, streamed straight from the database.
+// @Description  The status is scheduled, active, expired, exhausted, draft, paused or archived.
+// @Tags         Export
+// @Produce      text/csv
+// @Param        from			query		string				false	"First creation date (2006-01-02)"
+// @Param        to				query		string				false	"Last creation date (2006-01-02)"
+// @Param        prefix			query		string				false	"Code prefix"
+// @Param        status			query		string				false	"Status"
+// @Success      200			{file}		file
+// @Failure      400  			{object}	Error
+// @Router       	/export/discount		[get]
+func (h ExportHandler) ExportDiscounts(ctx *gin.Context) {
+	h.write(ctx, "discounts.csv", h.export.WriteDiscounts)
+}
+
+// ExportRedemptions godoc
+// @Summary      Export redemptions
+// @Description  Download the redemptions made between two dates as CSV, streamed straight from the database.
+// @Tags         Export
+// @Produce      text/csv
+// @Param        from			query		string				false	"First redemption date (2006-01-02)"
+// @Param        to				query		string				false	"Last redemption date (2006-01-02)"
+// @Param        prefix			query		string				false	"Code prefix"
+// @Param        status			query		string				false	"Status (active or reversed)"
+// @Param        type			query		string				false	"Code type (gift or discount)"
+// @Success      200			{file}		file
+// @Failure      400  			{object}	Error
+// @Router       	/export/redemption		[get]
+func (h ExportHandler) ExportRedemptions(ctx *gin.Context) {
+	h.write(ctx, "redemptions.csv", h.export.WriteRedemptions)
+}
+
+// write binds the export filters and streams the export as a CSV attachment.
+func (h ExportHandler) write(ctx *gin.Context, filename string, fn func(r *export.Request, w io.Writer) error) {
+	var req export.Request
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	w := &attachmentWriter{ctx: ctx, filename: filename, contentType: "text/csv"}
+	err := fn(&req, w)
+	if err != nil {
+		if w.started {
+			log.Error().Err(err).Str("export", filename).Msg("failed to stream export")
+			return
+		}
+		handleError(ctx, err)
+	}
+}
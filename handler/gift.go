@@ -3,33 +3,53 @@ package handler
 import (
 	"discount/server"
 	"discount/service/gift"
+	"discount/service/idempotency"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
 )
 
 type GiftHandler struct {
-	gift *gift.Service
+	gift        *gift.Service
+	idempotency *idempotency.Service
 }
 
-func NewGiftHandler(gift *gift.Service) GiftHandler {
+func NewGiftHandler(gift *gift.Service, idempotency *idempotency.Service) GiftHandler {
 	return GiftHandler{
-		gift: gift,
+		gift:        gift,
+		idempotency: idempotency,
 	}
 }
 
 func SetupGiftRoutes(s *server.Server, h GiftHandler) {
 	g := s.Engine.Group("/gift")
 	g.POST("", h.InitGift)
+	g.GET("", h.ListGifts)
 	g.GET("/:giftCode", h.GetGift)
-	g.POST("/use/:giftCode", h.UseGift)
+	g.PUT("/:giftCode", h.UpdateGift)
+	g.DELETE("/:giftCode", h.DeleteGift)
+	g.POST("/restore/:giftCode", h.RestoreGift)
+	g.POST("/use/:giftCode", idempotent(h.idempotency), h.UseGift)
+	g.POST("/reserve/:giftCode", idempotent(h.idempotency), h.ReserveGift)
+	g.POST("/reservation/:reservationID/confirm", idempotent(h.idempotency), h.ConfirmReservation)
+	g.POST("/reservation/:reservationID/cancel", h.CancelReservation)
+	g.POST("/debit/:giftCode", idempotent(h.idempotency), h.DebitGift)
+	g.GET("/balance/:giftCode", h.GetBalance)
+	g.POST("/pause/:giftCode", h.PauseGift)
+	g.POST("/resume/:giftCode", h.ResumeGift)
+	g.POST("/archive/:giftCode", h.ArchiveGift)
 }
 
 // InitGift godoc
 // @Summary			Initialize gift
 // @Description		Initialize a new gift.
+// @Description		Dates are RFC3339 timestamps, or dates such as 2024-03-19 taken as midnight in the business
+// @Description		timezone.
 // @Tags			GiftDTO
 // @Accept			json
 // @Produce      	json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
 // @Param        body			body		gift.CreateRequest		true	"Gift init request"
 // @Success      200			{object}	gift.DTO
 // @Failure      	400  			{object}	Error
@@ -41,7 +61,45 @@ func (h GiftHandler) InitGift(ctx *gin.Context) {
 		handleError(ctx, err)
 		return
 	}
-	result, err := h.gift.Create(&req)
+	result, err := h.gift.Create(getActor(ctx), &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListGifts godoc
+// @Summary      List gifts
+// @Description  List gifts page by page, newest first unless sorted otherwise, with the total number of matches.
+// @Description  The status is scheduled, active, expired, exhausted, draft, paused or archived. The sort is a comma
+// @Description  separated list of code, giftAmount, usedCount, startDateTime, expirationDate and createdAt, each
+// @Description  in descending order when prefixed with a minus, such as -giftAmount,code.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        prefix			query		string				false	"Code prefix"
+// @Param        minAmount		query		int					false	"Lowest gift amount"
+// @Param        maxAmount		query		int					false	"Highest gift amount"
+// @Param        expiresFrom	query		string				false	"First expiration date (2006-01-02)"
+// @Param        expiresTo		query		string				false	"Last expiration date (2006-01-02)"
+// @Param        status			query		string				false	"Status"
+// @Param        campaignId		query		int					false	"Campaign ID"
+// @Param        sort			query		string				false	"Sort fields"
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	gift.ListDTO
+// @Failure      400  			{object}	Error
+// @Router       	/gift		[get]
+func (h GiftHandler) ListGifts(ctx *gin.Context) {
+	var req gift.ListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	page, pageSize := getPaginationParams(ctx)
+
+	result, err := h.gift.List(&req, page, pageSize)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -51,12 +109,13 @@ func (h GiftHandler) InitGift(ctx *gin.Context) {
 
 // GetGift godoc
 // @Summary      Get gift
-// @Description  Get a gift by code.
+// @Description  Get a gift by code. The ETag header holds its version, to be sent back as If-Match on update.
 // @Tags         GiftDTO
 // @Accept       json
 // @Produce      json
 // @Param        giftCode		path		string				true	"Gift code"
 // @Success      200			{object}	gift.DTO
+// @Header       200			{string}	ETag				"Version of the gift"
 // @Failure      400  			{object}	Error
 // @Router       	/gift/{giftCode}		[get]
 func (h GiftHandler) GetGift(ctx *gin.Context) {
@@ -71,18 +130,119 @@ func (h GiftHandler) GetGift(ctx *gin.Context) {
 		handleError(ctx, err)
 		return
 	}
+	setETag(ctx, result.Version)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// UpdateGift godoc
+// @Summary      Update gift
+// @Description  Update a gift by code. The update only applies to the version in the If-Match header, or else in
+// @Description  the version field, and fails with 412 when the gift has been changed since that version.
+// @Description  The used count and remaining balance are kept as they are.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        If-Match		header		string				false	"ETag of the version being updated"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Param        body			body		gift.DTO			true	"Gift"
+// @Success      200			{object}	gift.DTO
+// @Header       200			{string}	ETag				"Version of the gift"
+// @Failure      400  			{object}	Error
+// @Failure      412  			{object}	Error
+// @Router       	/gift/{giftCode}		[put]
+func (h GiftHandler) UpdateGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req gift.DTO
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	version, err := ifMatch(ctx, req.Version)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	req.Code = giftCode
+	req.Version = version
+
+	result, err := h.gift.UpdateByCode(getActor(ctx), &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	setETag(ctx, result.Version)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DeleteGift godoc
+// @Summary      Delete gift
+// @Description  Delete a gift by code. It can be restored until it is purged after the retention period.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      204
+// @Failure      400  			{object}	Error
+// @Router       	/gift/{giftCode}		[delete]
+func (h GiftHandler) DeleteGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	err := h.gift.DeleteByCode(getActor(ctx), giftCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// RestoreGift godoc
+// @Summary      Restore gift
+// @Description  Bring back a deleted gift that has not been purged yet.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      200			{object}	gift.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/gift/restore/{giftCode}		[post]
+func (h GiftHandler) RestoreGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.Restore(getActor(ctx), giftCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
 	ctx.JSON(http.StatusOK, result)
 }
 
 // UseGift godoc
 // @Summary      Use gift
-// @Description  Use a gift code.
+// @Description  Use a gift code. Retries carrying the same Idempotency-Key header get the original response back.
 // @Tags         GiftDTO
 // @Accept       json
 // @Produce      json
 // @Param        giftCode		path		string				true	"Gift code"
+// @Param        Idempotency-Key	header		string				false	"Idempotency key"
+// @Param        body			body		gift.UseRequest		false	"Use request"
 // @Success      200			{object}	gift.DTO
 // @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
 // @Router       	/gift/use/{giftCode}		[post]
 func (h GiftHandler) UseGift(ctx *gin.Context) {
 	giftCode := ctx.Param("giftCode")
@@ -90,8 +250,239 @@ func (h GiftHandler) UseGift(ctx *gin.Context) {
 		ctx.AbortWithStatus(http.StatusNotFound)
 		return
 	}
+	var req gift.UseRequest
+	if err := ctx.ShouldBind(&req); err != nil && !errors.Is(err, io.EOF) {
+		handleError(ctx, err)
+		return
+	}
+
+	result, err := h.gift.UseGift(giftCode, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ReserveGift godoc
+// @Summary      Reserve gift
+// @Description  Hold one usage of a gift code for a checkout. The hold counts toward the usage limit until it is
+// @Description  confirmed, cancelled or expires.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        giftCode		path		string				true	"Gift code"
+// @Param        Idempotency-Key	header		string				false	"Idempotency key"
+// @Param        body			body		gift.ReserveRequest	true	"Reserve request"
+// @Success      200			{object}	gift.ReservationDTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/reserve/{giftCode}		[post]
+func (h GiftHandler) ReserveGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req gift.ReserveRequest
+	if err := ctx.ShouldBind(&req); err != nil && !errors.Is(err, io.EOF) {
+		handleError(ctx, err)
+		return
+	}
+
+	result, err := h.gift.Reserve(giftCode, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ConfirmReservation godoc
+// @Summary      Confirm gift reservation
+// @Description  Turn the gift usage held by a reservation into a redemption.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        reservationID	path		string				true	"Reservation ID"
+// @Param        Idempotency-Key	header		string				false	"Idempotency key"
+// @Success      200			{object}	gift.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/reservation/{reservationID}/confirm		[post]
+func (h GiftHandler) ConfirmReservation(ctx *gin.Context) {
+	reservationID := ctx.Param("reservationID")
+	if reservationID == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.ConfirmReservation(reservationID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// CancelReservation godoc
+// @Summary      Cancel gift reservation
+// @Description  Release the gift usage held by a reservation.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        reservationID	path		string				true	"Reservation ID"
+// @Success      200			{object}	gift.ReservationDTO
+// @Failure      400  			{object}	Error
+// @Router       	/gift/reservation/{reservationID}/cancel		[post]
+func (h GiftHandler) CancelReservation(ctx *gin.Context) {
+	reservationID := ctx.Param("reservationID")
+	if reservationID == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.CancelReservation(reservationID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DebitGift godoc
+// @Summary      Debit gift balance
+// @Description  Spend an amount from the balance of a balance mode gift. Retries carrying the same Idempotency-Key
+// @Description  header get the original response back.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        giftCode		path		string				true	"Gift code"
+// @Param        Idempotency-Key	header		string				false	"Idempotency key"
+// @Param        body			body		gift.DebitRequest	true	"Debit request"
+// @Success      200			{object}	gift.BalanceDTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/debit/{giftCode}		[post]
+func (h GiftHandler) DebitGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	var req gift.DebitRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	result, err := h.gift.Debit(giftCode, &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// GetBalance godoc
+// @Summary      Get gift balance
+// @Description  Get the remaining balance of a balance mode gift.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      200			{object}	gift.BalanceDTO
+// @Failure      400  			{object}	Error
+// @Router       	/gift/balance/{giftCode}		[get]
+func (h GiftHandler) GetBalance(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.Balance(giftCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// PauseGift godoc
+// @Summary      Pause gift
+// @Description  Stop a gift code from being used until it is resumed. It takes effect on the next use.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      200			{object}	gift.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/pause/{giftCode}		[post]
+func (h GiftHandler) PauseGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.Pause(getActor(ctx), giftCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ResumeGift godoc
+// @Summary      Resume gift
+// @Description  Make a paused or draft gift code usable again.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      200			{object}	gift.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/resume/{giftCode}		[post]
+func (h GiftHandler) ResumeGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.gift.Resume(getActor(ctx), giftCode)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ArchiveGift godoc
+// @Summary      Archive gift
+// @Description  Retire a gift code for good, keeping its redemptions and history.
+// @Tags         GiftDTO
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        giftCode		path		string				true	"Gift code"
+// @Success      200			{object}	gift.DTO
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/gift/archive/{giftCode}		[post]
+func (h GiftHandler) ArchiveGift(ctx *gin.Context) {
+	giftCode := ctx.Param("giftCode")
+	if giftCode == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
 
-	result, err := h.gift.UseGift(giftCode)
+	result, err := h.gift.Archive(getActor(ctx), giftCode)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -3,7 +3,9 @@ package handler
 import (
 	"discount/internal/locale"
 	"discount/internal/serr"
+	"discount/service/audit"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
@@ -45,15 +47,60 @@ func getTraceID(ctx *gin.Context) string {
 	return ""
 }
 
+// actorHeader names who makes a request when it carries no authenticated principal.
+const actorHeader = "X-Actor"
+
+// getActor returns who makes the request, for the audit log: the principal an authentication middleware stored
+// under "actor", or else the X-Actor header.
+func getActor(ctx *gin.Context) audit.Actor {
+	id := ctx.GetString("actor")
+	if id == "" {
+		id = ctx.GetHeader(actorHeader)
+	}
+	return audit.Actor{ID: id, TraceID: getTraceID(ctx)}
+}
+
+// setETag sends the version of a gift or discount as the ETag of the response.
+func setETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatch returns the version an update is conditional on: the ETag in the If-Match header, or else version, the
+// version given in the request body. An update without a version is rejected.
+func ifMatch(ctx *gin.Context, version int64) (int64, error) {
+	if etag := ctx.GetHeader("If-Match"); etag != "" {
+		tag, err := strconv.Unquote(etag)
+		if err != nil {
+			return 0, serr.ValidationErr("If-Match", "invalid version", serr.ErrInvalidVersion)
+		}
+		version, err = strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			return 0, serr.ValidationErr("If-Match", "invalid version", serr.ErrInvalidVersion)
+		}
+	}
+	if version <= 0 {
+		return 0, serr.ValidationErr("version", "invalid version", serr.ErrInvalidVersion)
+	}
+	return version, nil
+}
+
 type Error struct {
 	Message string         `json:"message"`
 	Code    serr.ErrorCode `json:"code"`
 	TraceID string         `json:"trace_id"`
 }
 
+// localizeError builds the response envelope of a service error in the language of the request.
+func localizeError(ctx *gin.Context, e *serr.ServiceError) *Error {
+	return &Error{
+		Message: locale.Localize(e.Message, getLanguage(ctx)),
+		Code:    e.ErrorCode,
+		TraceID: getTraceID(ctx),
+	}
+}
+
 func handleError(ctx *gin.Context, err error) {
 	tID := getTraceID(ctx)
-	lang := getLanguage(ctx)
 	switch err.(type) {
 	case *serr.ServiceError:
 		var e *serr.ServiceError
@@ -63,14 +110,7 @@ func handleError(ctx *gin.Context, err error) {
 			l.Err(e.Cause)
 		}
 		l.Msg(e.Message)
-		ctx.AbortWithStatusJSON(
-			e.Code,
-			Error{
-				Message: locale.Localize(e.Message, lang),
-				Code:    e.ErrorCode,
-				TraceID: tID,
-			},
-		)
+		ctx.AbortWithStatusJSON(e.Code, localizeError(ctx, e))
 		return
 	case *pq.Error:
 		var e *pq.Error
@@ -89,3 +129,22 @@ func handleError(ctx *gin.Context, err error) {
 		return
 	}
 }
+
+// attachmentWriter streams a file download to the client. The download headers are only sent with the first
+// write, so an error found before anything is written can still be returned as a regular error response.
+type attachmentWriter struct {
+	ctx         *gin.Context
+	filename    string
+	contentType string
+	started     bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.ctx.Header("Content-Type", w.contentType)
+		w.ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.ctx.Status(http.StatusOK)
+	}
+	return w.ctx.Writer.Write(p)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"discount/service/idempotency"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+	"io"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotent makes a route safe to retry. A request carrying an Idempotency-Key header that has already been
+// answered successfully gets the stored response back without running the handler again, provided its body is the
+// same as the one of the first request.
+func idempotent(idem *idempotency.Service) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		scope := ctx.Request.Method + " " + ctx.Request.URL.Path
+		request, err := requestHash(ctx)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+
+		stored, err := idem.Begin(scope, key, request)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+		if stored != nil {
+			ctx.Header("Idempotent-Replayed", "true")
+			ctx.Data(stored.StatusCode, gin.MIMEJSON, stored.Body)
+			ctx.Abort()
+			return
+		}
+
+		w := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+		ctx.Next()
+		idem.Complete(scope, key, request, w.Status(), w.body.Bytes())
+	}
+}
+
+// requestHash returns the SHA-256 hash of the request body, hex encoded, and leaves the body to be read again by the
+// handler.
+func requestHash(ctx *gin.Context) (string, error) {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return "", err
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// responseRecorder keeps a copy of the response body written through it.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
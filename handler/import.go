@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/importer"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type ImportHandler struct {
+	importer *importer.Service
+}
+
+func NewImportHandler(importer *importer.Service) ImportHandler {
+	return ImportHandler{
+		importer: importer,
+	}
+}
+
+func SetupImportRoutes(s *server.Server, h ImportHandler) {
+	g := s.Engine.Group("/import")
+	g.POST("/:type", h.Import)
+}
+
+type ImportResponse struct {
+	*importer.ReportDTO
+	Errors []ImportLineErrorResponse `json:"errors"`
+}
+
+type ImportLineErrorResponse struct {
+	*importer.LineErrorDTO
+	Error *Error `json:"error"`
+}
+
+// Import godoc
+// @Summary      Import codes
+// @Description  Import existing gift or discount codes, keeping their used counts, from a CSV file with a header row
+// @Description  or a JSON Lines file sent as the request body. CSV columns and JSON fields are named like the fields
+// @Description  of the create request, plus usedCount (and remainingBalance for gifts). Nothing is written unless
+// @Description  every line is valid; the lines that are not are listed under errors. With dryRun the file is only
+// @Description  checked.
+// @Tags         Import
+// @Accept       plain
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        type			path		string				true	"Code type (gift or discount)"
+// @Param        format			query		string				false	"File format (csv or jsonl), taken from the content type when empty"
+// @Param        dryRun			query		bool				false	"Only check the file"
+// @Success      200			{object}	ImportResponse
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Failure      500  			{object}	Error
+// @Router       	/import/{type}		[post]
+func (h ImportHandler) Import(ctx *gin.Context) {
+	var req importer.Request
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	req.Type = ctx.Param("type")
+	if req.Format == "" {
+		req.Format = importer.FormatCSV
+		if strings.Contains(ctx.ContentType(), "json") {
+			req.Format = importer.FormatJSONL
+		}
+	}
+
+	result, err := h.importer.Import(getActor(ctx), &req, ctx.Request.Body)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	resp := ImportResponse{ReportDTO: result, Errors: make([]ImportLineErrorResponse, 0, len(result.Errors))}
+	for _, e := range result.Errors {
+		resp.Errors = append(resp.Errors, ImportLineErrorResponse{LineErrorDTO: e, Error: localizeError(ctx, e.Failure)})
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
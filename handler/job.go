@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/job"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+)
+
+type JobHandler struct {
+	job *job.Service
+}
+
+func NewJobHandler(job *job.Service) JobHandler {
+	return JobHandler{
+		job: job,
+	}
+}
+
+func SetupJobRoutes(s *server.Server, h JobHandler) {
+	g := s.Engine.Group("/job")
+	g.POST("", h.CreateJob)
+	g.GET("/:jobID", h.GetJob)
+	g.GET("/:jobID/download", h.DownloadJob)
+}
+
+// CreateJob godoc
+// @Summary      Create bulk generation job
+// @Description  Generate count gift or discount codes from a template in the background. The job starts as pending;
+// @Description  follow its progress with GET /job/{jobID} and download the codes once it has completed.
+// @Tags         Job
+// @Accept       json
+// @Produce      json
+// @Param        X-Actor			header		string				false	"Who makes the change, for the audit log"
+// @Param        body			body		job.CreateRequest	true	"Job request"
+// @Success      202			{object}	job.DTO
+// @Failure      400  			{object}	Error
+// @Failure      500  			{object}	Error
+// @Router       	/job		[post]
+func (h JobHandler) CreateJob(ctx *gin.Context) {
+	var req job.CreateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	result, err := h.job.Create(getActor(ctx), &req)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, result)
+}
+
+// GetJob godoc
+// @Summary      Get bulk generation job
+// @Description  Get the status and progress of a bulk generation job. A job that makes no progress for 10 minutes,
+// @Description  such as one interrupted by a restart, is marked failed; the codes it generated are kept.
+// @Tags         Job
+// @Accept       json
+// @Produce      json
+// @Param        jobID			path		int					true	"Job ID"
+// @Success      200			{object}	job.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/job/{jobID}		[get]
+func (h JobHandler) GetJob(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("jobID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.job.GetByID(id)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DownloadJob godoc
+// @Summary      Download bulk generation job
+// @Description  Download the codes generated by a completed job as CSV.
+// @Tags         Job
+// @Produce      text/csv
+// @Param        jobID			path		int					true	"Job ID"
+// @Success      200			{file}		file
+// @Failure      400  			{object}	Error
+// @Failure      409  			{object}	Error
+// @Router       	/job/{jobID}/download		[get]
+func (h JobHandler) DownloadJob(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("jobID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	w := &attachmentWriter{ctx: ctx, filename: fmt.Sprintf("job-%d.csv", id), contentType: "text/csv"}
+	err = h.job.WriteCSV(id, w)
+	if err != nil {
+		if w.started {
+			log.Error().Err(err).Int64("job", id).Msg("failed to stream job codes")
+			return
+		}
+		handleError(ctx, err)
+	}
+}
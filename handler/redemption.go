@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"discount/server"
+	"discount/service/redemption"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type RedemptionHandler struct {
+	redemption *redemption.Service
+}
+
+func NewRedemptionHandler(redemption *redemption.Service) RedemptionHandler {
+	return RedemptionHandler{
+		redemption: redemption,
+	}
+}
+
+func SetupRedemptionRoutes(s *server.Server, h RedemptionHandler) {
+	g := s.Engine.Group("/redemption")
+	g.GET("/code/:code", h.ListByCode)
+	g.GET("/user/:userID", h.ListByUser)
+	g.POST("/reverse/:redemptionID", h.Reverse)
+	g.POST("/order/:orderID/reverse", h.ReverseByOrder)
+}
+
+// ListByCode godoc
+// @Summary      List redemptions of a code
+// @Description  List the redemptions of a gift or discount code page by page, newest first.
+// @Tags         Redemption
+// @Accept       json
+// @Produce      json
+// @Param        code			path		string				true	"Gift or discount code"
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	redemption.ListDTO
+// @Failure      500  			{object}	Error
+// @Router       	/redemption/code/{code}		[get]
+func (h RedemptionHandler) ListByCode(ctx *gin.Context) {
+	code := ctx.Param("code")
+	if code == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	page, pageSize := getPaginationParams(ctx)
+
+	result, err := h.redemption.ListByCode(code, page, pageSize)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ListByUser godoc
+// @Summary      List redemptions of a user
+// @Description  List the redemptions made by a user page by page, newest first.
+// @Tags         Redemption
+// @Accept       json
+// @Produce      json
+// @Param        userID			path		string				true	"User ID"
+// @Param        page			query		int					false	"Page number"
+// @Param        page_size		query		int					false	"Page size"
+// @Success      200			{object}	redemption.ListDTO
+// @Failure      500  			{object}	Error
+// @Router       	/redemption/user/{userID}		[get]
+func (h RedemptionHandler) ListByUser(ctx *gin.Context) {
+	userID := ctx.Param("userID")
+	if userID == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	page, pageSize := getPaginationParams(ctx)
+
+	result, err := h.redemption.ListByUser(userID, page, pageSize)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Reverse godoc
+// @Summary      Reverse redemption
+// @Description  Restore the usage consumed by a redemption, for example when its order is refunded.
+// @Description  A redemption can only be reversed once.
+// @Tags         Redemption
+// @Accept       json
+// @Produce      json
+// @Param        redemptionID	path		int					true	"Redemption ID"
+// @Success      200			{object}	redemption.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/redemption/reverse/{redemptionID}		[post]
+func (h RedemptionHandler) Reverse(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("redemptionID"), 10, 64)
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.redemption.Reverse(id)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ReverseByOrder godoc
+// @Summary      Reverse order redemptions
+// @Description  Restore the usages consumed by every redemption of an order that has not been reversed yet.
+// @Tags         Redemption
+// @Accept       json
+// @Produce      json
+// @Param        orderID		path		string				true	"Order ID"
+// @Success      200			{array}		redemption.DTO
+// @Failure      400  			{object}	Error
+// @Router       	/redemption/order/{orderID}/reverse		[post]
+func (h RedemptionHandler) ReverseByOrder(ctx *gin.Context) {
+	orderID := ctx.Param("orderID")
+	if orderID == "" {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	result, err := h.redemption.ReverseByOrder(orderID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, result)
+}
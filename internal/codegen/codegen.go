@@ -0,0 +1,154 @@
+// Package codegen generates random gift and discount codes that end in a check character.
+//
+// The random part of a code is drawn from crypto/rand, so codes cannot be guessed from each other. The check
+// character is computed with the Luhn mod N algorithm over the alphabet, which catches every single mistyped
+// character and most swaps of adjacent characters before the code is looked up anywhere.
+package codegen
+
+import (
+	"crypto/rand"
+	"discount/internal/serr"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// DefaultAlphabet leaves out characters that are easily confused with each other: 0/O and 1/I.
+const DefaultAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// prefixSeparator separates the prefix of a code from its generated part.
+const prefixSeparator = "-"
+
+var ErrInvalidConfig = errors.New("invalid code generator config")
+
+// Generator creates codes of Length random characters followed by a check character. The generated part is
+// split into groups of GroupSize characters joined by Separator; a zero GroupSize disables grouping.
+type Generator struct {
+	alphabet  []rune
+	index     map[rune]int
+	length    int
+	groupSize int
+	separator string
+}
+
+func New(alphabet string, length, groupSize int, separator string) (*Generator, error) {
+	g := &Generator{
+		alphabet:  []rune(alphabet),
+		index:     make(map[rune]int, len(alphabet)),
+		length:    length,
+		groupSize: groupSize,
+		separator: separator,
+	}
+	for i, r := range g.alphabet {
+		if _, ok := g.index[r]; ok || strings.ContainsRune(separator+prefixSeparator, r) {
+			return nil, ErrInvalidConfig
+		}
+		g.index[r] = i
+	}
+	if len(g.alphabet) < 2 || length <= 0 || groupSize < 0 {
+		return nil, ErrInvalidConfig
+	}
+	return g, nil
+}
+
+// Generate returns a new random code starting with prefix.
+func (g *Generator) Generate(prefix string) (string, error) {
+	body := make([]rune, g.length, g.length+1)
+	n := big.NewInt(int64(len(g.alphabet)))
+	for i := range body {
+		j, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		body[i] = g.alphabet[j.Int64()]
+	}
+	body = append(body, g.checkChar(body))
+	code := g.format(body)
+	if prefix != "" {
+		code = prefix + prefixSeparator + code
+	}
+	return code, nil
+}
+
+// Check rejects a code that has the shape of a generated code but whose check character does not match.
+// Codes of any other shape, such as codes chosen by hand or imported from elsewhere, are accepted as they are.
+func (g *Generator) Check(code string) error {
+	body, ok := g.parse(code)
+	if !ok || g.valid(body) {
+		return nil
+	}
+	return serr.ValidationErr("code", "invalid code check character", serr.ErrInvalidCheckCharacter)
+}
+
+// format splits the generated part of a code into groups.
+func (g *Generator) format(body []rune) string {
+	if g.groupSize == 0 {
+		return string(body)
+	}
+	groups := make([]string, 0, len(body)/g.groupSize+1)
+	for i := 0; i < len(body); i += g.groupSize {
+		end := i + g.groupSize
+		if end > len(body) {
+			end = len(body)
+		}
+		groups = append(groups, string(body[i:end]))
+	}
+	return strings.Join(groups, g.separator)
+}
+
+// parse returns the generated part of the code, including the check character, if the code has the shape of
+// a generated code.
+func (g *Generator) parse(code string) ([]rune, bool) {
+	runes := []rune(code)
+	size := g.formattedSize()
+	if len(runes) < size {
+		return nil, false
+	}
+	prefix, formatted := runes[:len(runes)-size], runes[len(runes)-size:]
+	if len(prefix) > 0 && !strings.HasSuffix(string(prefix), prefixSeparator) {
+		return nil, false
+	}
+	body := []rune(strings.ReplaceAll(string(formatted), g.separator, ""))
+	if len(body) != g.length+1 || g.format(body) != string(formatted) {
+		return nil, false
+	}
+	for _, r := range body {
+		if _, ok := g.index[r]; !ok {
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+// formattedSize is the length of the generated part of a code, including the check character and separators.
+func (g *Generator) formattedSize() int {
+	size := g.length + 1
+	if g.groupSize > 0 {
+		size += (g.length / g.groupSize) * len([]rune(g.separator))
+	}
+	return size
+}
+
+// checkChar computes the Luhn mod N check character of body.
+func (g *Generator) checkChar(body []rune) rune {
+	n := len(g.alphabet)
+	sum := g.sum(body, 2)
+	return g.alphabet[(n-sum%n)%n]
+}
+
+// valid reports whether body, ending in its check character, passes the Luhn mod N check.
+func (g *Generator) valid(body []rune) bool {
+	return g.sum(body, 1)%len(g.alphabet) == 0
+}
+
+// sum adds up the Luhn mod N addends of body from right to left, starting with the given factor.
+func (g *Generator) sum(body []rune, factor int) int {
+	n := len(g.alphabet)
+	sum := 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * g.index[body[i]]
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return sum
+}
@@ -0,0 +1,99 @@
+package codegen_test
+
+import (
+	"discount/internal/codegen"
+	"regexp"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	g, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	format := regexp.MustCompile(`^NOWRUZ-[2-9A-HJ-NP-Z]{4}-[2-9A-HJ-NP-Z]{4}-[2-9A-HJ-NP-Z]{3}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := g.Generate("NOWRUZ")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected code format %q", code)
+		}
+		if err = g.Check(code); err != nil {
+			t.Fatalf("expected %q to pass the check, got %v", code, err)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestCheck(t *testing.T) {
+	g, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	code, err := g.Generate("TEST")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	prefix, body := code[:5], []rune(code[5:])
+
+	for i, r := range body {
+		if r == '-' {
+			continue
+		}
+		for _, c := range codegen.DefaultAlphabet {
+			if c == r {
+				continue
+			}
+			typo := append([]rune{}, body...)
+			typo[i] = c
+			if g.Check(prefix+string(typo)) == nil {
+				t.Fatalf("expected typo %q of %q to fail the check", prefix+string(typo), code)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{name: "hand picked code", code: "SUMMER"},
+		{name: "timestamp code", code: "TEST-123456"},
+		{name: "other grouping", code: "TEST-ABCDE-FGHJK-L"},
+		{name: "character outside the alphabet", code: prefix + "0" + string(body[1:])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := g.Check(tt.code); err != nil {
+				t.Fatalf("expected %q to be accepted as is, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		alphabet  string
+		length    int
+		groupSize int
+		separator string
+	}{
+		{name: "duplicate characters", alphabet: "AAB", length: 8},
+		{name: "separator in alphabet", alphabet: "AB-", length: 8},
+		{name: "single character", alphabet: "A", length: 8},
+		{name: "no length", alphabet: codegen.DefaultAlphabet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codegen.New(tt.alphabet, tt.length, tt.groupSize, tt.separator); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
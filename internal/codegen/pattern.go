@@ -0,0 +1,157 @@
+package codegen
+
+import (
+	"crypto/rand"
+	"discount/internal/serr"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	digitPlaceholder  = '#'
+	letterPlaceholder = '?'
+	digits            = "0123456789"
+	letters           = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// maxPatternUsage is the share of the codes a pattern allows that may be issued. Beyond it, random codes would
+// collide with issued ones too often to be generated reliably.
+const maxPatternUsage = 0.5
+
+// Pattern is a code template such as "NOWRUZ-####-??", in which # stands for a random digit and ? for a random
+// letter. Every other character is copied into the code as it is.
+type Pattern struct {
+	pattern []rune
+}
+
+// ParsePattern parses a code pattern. The pattern must contain at least one placeholder, and it must not be able
+// to produce codes shaped like the ones g generates, which would then be taken for mistyped generated codes.
+func (g *Generator) ParsePattern(pattern string) (*Pattern, error) {
+	p := &Pattern{pattern: []rune(pattern)}
+	if p.placeholders() == 0 {
+		return nil, serr.ValidationErr("pattern", "invalid code pattern", serr.ErrInvalidPattern)
+	}
+	if g.matchesShape(p.pattern) {
+		return nil, serr.ValidationErr("pattern", "code pattern clashes with generated codes", serr.ErrInvalidPattern)
+	}
+	return p, nil
+}
+
+// Capacity is the number of different codes the pattern allows, capped at math.MaxInt64.
+func (p *Pattern) Capacity() int64 {
+	capacity := 1.0
+	for _, r := range p.pattern {
+		switch r {
+		case digitPlaceholder:
+			capacity *= float64(len(digits))
+		case letterPlaceholder:
+			capacity *= float64(len(letters))
+		}
+	}
+	if capacity >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(capacity)
+}
+
+// Check refuses to issue requested more codes from the pattern when used codes matching it already exist and
+// the total would take up more than half of the codes the pattern allows.
+func (p *Pattern) Check(used, requested int64) error {
+	if float64(used)+float64(requested) > float64(p.Capacity())*maxPatternUsage {
+		return serr.ValidationErr("pattern", "code pattern does not allow that many codes", serr.ErrPatternExhausted)
+	}
+	return nil
+}
+
+// Generate returns a new random code matching the pattern.
+func (p *Pattern) Generate() (string, error) {
+	code := make([]rune, len(p.pattern))
+	for i, r := range p.pattern {
+		var err error
+		switch r {
+		case digitPlaceholder:
+			code[i], err = randomChar(digits)
+		case letterPlaceholder:
+			code[i], err = randomChar(letters)
+		default:
+			code[i] = r
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(code), nil
+}
+
+// Like returns a SQL LIKE expression matching every code the pattern can produce.
+func (p *Pattern) Like() string {
+	var b strings.Builder
+	for _, r := range p.pattern {
+		switch r {
+		case digitPlaceholder, letterPlaceholder:
+			b.WriteRune('_')
+		case '%', '_', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (p *Pattern) placeholders() int {
+	n := 0
+	for _, r := range p.pattern {
+		if r == digitPlaceholder || r == letterPlaceholder {
+			n++
+		}
+	}
+	return n
+}
+
+// matchesShape reports whether some code produced by the pattern could have the shape of a generated code.
+func (g *Generator) matchesShape(pattern []rune) bool {
+	size := g.formattedSize()
+	if len(pattern) < size {
+		return false
+	}
+	prefix, formatted := pattern[:len(pattern)-size], pattern[len(pattern)-size:]
+	if len(prefix) > 0 && !strings.HasSuffix(string(prefix), prefixSeparator) {
+		return false
+	}
+	shape := []rune(g.format(make([]rune, g.length+1)))
+	for i, r := range formatted {
+		if shape[i] != 0 {
+			if r != shape[i] {
+				return false
+			}
+			continue
+		}
+		if !g.canHold(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// canHold reports whether a pattern character can turn into a character of the alphabet.
+func (g *Generator) canHold(r rune) bool {
+	switch r {
+	case digitPlaceholder:
+		return strings.ContainsAny(digits, string(g.alphabet))
+	case letterPlaceholder:
+		return strings.ContainsAny(letters, string(g.alphabet))
+	}
+	_, ok := g.index[r]
+	return ok
+}
+
+func randomChar(chars string) (rune, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return rune(chars[i.Int64()]), nil
+}
@@ -0,0 +1,95 @@
+package codegen_test
+
+import (
+	"discount/internal/codegen"
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestPatternGenerate(t *testing.T) {
+	g, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	p, err := g.ParsePattern("NOWRUZ-####-??")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	format := regexp.MustCompile(`^NOWRUZ-[0-9]{4}-[A-Z]{2}$`)
+	for i := 0; i < 1000; i++ {
+		code, err := p.Generate()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected code format %q", code)
+		}
+		if err = g.Check(code); err != nil {
+			t.Fatalf("expected %q to pass the check, got %v", code, err)
+		}
+	}
+	if like := p.Like(); like != "NOWRUZ-____-__" {
+		t.Fatalf("unexpected like expression %q", like)
+	}
+}
+
+func TestPatternCapacity(t *testing.T) {
+	g, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tests := []struct {
+		pattern  string
+		capacity int64
+	}{
+		{pattern: "NOWRUZ-####-??", capacity: 10000 * 676},
+		{pattern: "#", capacity: 10},
+		{pattern: "A?", capacity: 26},
+		{pattern: "????????????????????", capacity: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			p, err := g.ParsePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if c := p.Capacity(); c != tt.capacity {
+				t.Fatalf("expected capacity %d, got %d", tt.capacity, c)
+			}
+		})
+	}
+
+	p, err := g.ParsePattern("SALE-##")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = p.Check(0, 50); err != nil {
+		t.Fatalf("expected half of the codes to be allowed, got %v", err)
+	}
+	if err = p.Check(40, 11); err == nil {
+		t.Fatal("expected more than half of the codes to be refused")
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	g, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{name: "no placeholder", pattern: "NOWRUZ"},
+		{name: "empty", pattern: ""},
+		{name: "generated shape", pattern: "NOWRUZ-????-????-???"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := g.ParsePattern(tt.pattern); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -62,6 +63,66 @@ func RDB() int { return viper.GetInt("db.redis.db") }
 
 func RDBTimeOut() time.Duration { return viper.GetDuration("db.redis.timeout") }
 
+func IdempotencyRetention() time.Duration {
+	return viper.GetDuration("app.idempotency.retention")
+}
+
+// DeletedRetention is how long deleted gifts and discounts can be restored before they are purged.
+func DeletedRetention() time.Duration {
+	return viper.GetDuration("app.retention.deleted")
+}
+
+func ReservationTTL() time.Duration {
+	return viper.GetDuration("app.reservation.ttl")
+}
+
+func ReservationMaxTTL() time.Duration {
+	return viper.GetDuration("app.reservation.maxTTL")
+}
+
+func JobBatchSize() int {
+	return viper.GetInt("app.job.batchSize")
+}
+
+func JobMaxCount() int64 {
+	return viper.GetInt64("app.job.maxCount")
+}
+
+func ImportMaxRecords() int {
+	return viper.GetInt("app.import.maxRecords")
+}
+
+func CodeAlphabet() string {
+	return viper.GetString("app.code.alphabet")
+}
+
+func CodeLength() int {
+	return viper.GetInt("app.code.length")
+}
+
+func CodeGroupSize() int {
+	return viper.GetInt("app.code.groupSize")
+}
+
+func CodeSeparator() string {
+	return viper.GetString("app.code.separator")
+}
+
+// Timezone is the business timezone dates and recurring schedules are read in. It falls back to UTC when app.timezone is
+// not a known location.
+func Timezone() *time.Location {
+	return timezone()
+}
+
+var timezone = sync.OnceValue(func() *time.Location {
+	loc, err := time.LoadLocation(viper.GetString("app.timezone"))
+	if err != nil {
+		log.Error().Err(err).Msg("invalid timezone, falling back to UTC")
+		return time.UTC
+	}
+	return loc
+})
+
 func LogLevel() string {
 	return viper.GetString("app.log.level")
 }
@@ -0,0 +1,33 @@
+package date
+
+import (
+	"discount/internal/config"
+	"discount/internal/serr"
+	"time"
+)
+
+// Layout is the layout of a date given without a time of day.
+const Layout = "2006-01-02"
+
+// Parse reads value as an RFC3339 timestamp or as a date, which stands for midnight at the start of that day in the
+// business timezone. An empty value is the zero time. A value that is neither fails with a validation error of
+// the given field.
+func Parse(field, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	t, err = ParseDay(value)
+	if err != nil {
+		return time.Time{}, serr.ValidationErr(field, "invalid date", serr.ErrInvalidDate)
+	}
+	return t, nil
+}
+
+// ParseDay reads a date as midnight at the start of that day in the business timezone.
+func ParseDay(value string) (time.Time, error) {
+	return time.ParseInLocation(Layout, value, config.Timezone())
+}
@@ -0,0 +1,41 @@
+package date_test
+
+import (
+	"discount/internal/date"
+	"discount/internal/serr"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "", want: time.Time{}},
+		{value: "2024-03-19", want: time.Date(2024, 3, 19, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-19T23:59:59+03:30", want: time.Date(2024, 3, 19, 20, 29, 59, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := date.Parse("expirationDate", tt.value)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, value := range []string{"2024-13-01", "19/03/2024", "2024-03-19 10:00"} {
+		t.Run(value, func(t *testing.T) {
+			_, err := date.Parse("expirationDate", value)
+			var e *serr.ServiceError
+			if !errors.As(err, &e) || e.ErrorCode != serr.ErrInvalidDate || e.Method != "expirationDate" {
+				t.Fatalf("expected %v, got %v", serr.ErrInvalidDate, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Package deps provides the dependencies shared by the commands, built from the configuration.
+package deps
+
+import (
+	"database/sql"
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/config"
+	"github.com/redis/go-redis/v9"
+	"log"
+)
+
+func PostgresDB() *sql.DB {
+	psql, err := db.NewPostgres(
+		config.DBName(), config.DBUser(), config.DBPassword(), config.DBHost(), config.DBPort(),
+		config.DBMaxOpenConn(), config.DBMaxIdleConn(),
+	)
+	if err != nil {
+		log.Fatalf("failed to initalize db: %v", err)
+	}
+	return psql
+}
+
+func RedisDB() *redis.Client {
+	rdb, err := db.NewRedis(config.RDBHost(), config.RDBPassword(), config.RDBPort(), config.RDB(), config.RDBTimeOut())
+	if err != nil {
+		log.Fatalf("failed to initalize redis: %v", err)
+	}
+	return rdb
+}
+
+func CodeGenerator() (*codegen.Generator, error) {
+	return codegen.New(config.CodeAlphabet(), config.CodeLength(), config.CodeGroupSize(), config.CodeSeparator())
+}
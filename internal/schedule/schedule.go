@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"database/sql/driver"
+	"discount/internal/config"
+	"discount/internal/serr"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clock is the layout of the From and To times of a window.
+const clock = "15:04"
+
+// Window is a recurring period of the week, such as friday evenings. Days are weekday names like "friday"; no
+// days means every day. From and To are times of day, To exclusive. A window whose To is before its From runs past
+// midnight into the next day, and a window without From and To lasts the whole day.
+type Window struct {
+	Days []string `json:"days"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+}
+
+// Schedule restricts a code to the times within any of its windows, read in the configured timezone. Schedules
+// are stored as a JSON array, and an empty schedule does not restrict the code.
+type Schedule []Window
+
+// Validate checks that every window has known days and either both or none of From and To.
+func (s Schedule) Validate() error {
+	for _, w := range s {
+		if _, _, ok := w.minutes(); !ok {
+			return errInvalidSchedule()
+		}
+		for _, d := range w.Days {
+			if weekday(d) < 0 {
+				return errInvalidSchedule()
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether t falls within the schedule.
+func (s Schedule) Allows(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	t = t.In(config.Timezone())
+	return slices.ContainsFunc(s, func(w Window) bool { return w.allows(t) })
+}
+
+func (w Window) allows(t time.Time) bool {
+	from, to, _ := w.minutes()
+	m := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case from == to:
+		return w.on(day)
+	case from < to:
+		return w.on(day) && m >= from && m < to
+	}
+	return (w.on(day) && m >= from) || (w.on((day+6)%7) && m < to)
+}
+
+// on reports whether the window starts on the given day.
+func (w Window) on(day time.Weekday) bool {
+	return len(w.Days) == 0 || slices.ContainsFunc(w.Days, func(d string) bool { return weekday(d) == day })
+}
+
+// minutes returns From and To as minutes since midnight.
+func (w Window) minutes() (from, to int, ok bool) {
+	if w.From == "" && w.To == "" {
+		return 0, 0, true
+	}
+	f, err := time.Parse(clock, w.From)
+	if err != nil {
+		return 0, 0, false
+	}
+	t, err := time.Parse(clock, w.To)
+	if err != nil {
+		return 0, 0, false
+	}
+	return f.Hour()*60 + f.Minute(), t.Hour()*60 + t.Minute(), true
+}
+
+// weekday returns the weekday with the given name, or -1 when there is none.
+func weekday(name string) time.Weekday {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d
+		}
+	}
+	return -1
+}
+
+func errInvalidSchedule() error {
+	return serr.ValidationErr("schedule", "invalid schedule", serr.ErrInvalidSchedule)
+}
+
+func (s Schedule) Value() (driver.Value, error) {
+	if s == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s)
+}
+
+func (s *Schedule) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("schedule must be scanned from JSON")
+	}
+	return json.Unmarshal(b, s)
+}
@@ -0,0 +1,91 @@
+package schedule_test
+
+import (
+	"discount/internal/schedule"
+	"testing"
+	"time"
+)
+
+func TestScheduleAllows(t *testing.T) {
+	// 2024-03-15 is a friday.
+	at := func(day int, clock string) time.Time {
+		c, _ := time.Parse("15:04", clock)
+		return time.Date(2024, 3, day, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		schedule schedule.Schedule
+		at       time.Time
+		want     bool
+	}{
+		{name: "empty schedule", at: at(15, "03:00"), want: true},
+		{
+			name:     "weekend",
+			schedule: schedule.Schedule{{Days: []string{"friday", "saturday"}}},
+			at:       at(16, "23:59"),
+			want:     true,
+		},
+		{
+			name:     "not on weekend",
+			schedule: schedule.Schedule{{Days: []string{"friday", "saturday"}}},
+			at:       at(17, "00:00"),
+			want:     false,
+		},
+		{
+			name:     "happy hour",
+			schedule: schedule.Schedule{{From: "17:00", To: "19:00"}},
+			at:       at(14, "17:00"),
+			want:     true,
+		},
+		{
+			name:     "after happy hour",
+			schedule: schedule.Schedule{{From: "17:00", To: "19:00"}},
+			at:       at(14, "19:00"),
+			want:     false,
+		},
+		{
+			name:     "past midnight of the start day",
+			schedule: schedule.Schedule{{Days: []string{"Friday"}, From: "22:00", To: "02:00"}},
+			at:       at(16, "01:30"),
+			want:     true,
+		},
+		{
+			name:     "past midnight of another day",
+			schedule: schedule.Schedule{{Days: []string{"friday"}, From: "22:00", To: "02:00"}},
+			at:       at(15, "01:30"),
+			want:     false,
+		},
+		{
+			name: "any window",
+			schedule: schedule.Schedule{
+				{Days: []string{"monday"}},
+				{Days: []string{"thursday"}, From: "12:00", To: "14:00"},
+			},
+			at:   at(14, "13:00"),
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := tt.schedule.Allows(tt.at); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	tests := []schedule.Schedule{
+		{{Days: []string{"someday"}}},
+		{{From: "17:00"}},
+		{{From: "25:00", To: "26:00"}},
+	}
+	for _, s := range tests {
+		if err := s.Validate(); err == nil {
+			t.Fatalf("expected %v to be invalid", s)
+		}
+	}
+}
@@ -16,6 +16,76 @@ const (
 	ErrInvalidDiscountCode   ErrorCode = "INVALID_DISCOUNT_CODE"
 	ErrPermission            ErrorCode = "PERMISSION"
 	ErrGiftUsageLimitReached ErrorCode = "GIFT_USAGE_LIMIT_REACHED"
+	ErrGiftNotStarted        ErrorCode = "GIFT_NOT_STARTED"
+	ErrGiftExpired           ErrorCode = "GIFT_EXPIRED"
+
+	ErrInvalidAmount               ErrorCode = "INVALID_AMOUNT"
+	ErrInvalidCodeType             ErrorCode = "INVALID_CODE_TYPE"
+	ErrUnknownCode                 ErrorCode = "UNKNOWN_CODE"
+	ErrDiscountNotStarted          ErrorCode = "DISCOUNT_NOT_STARTED"
+	ErrDiscountExpired             ErrorCode = "DISCOUNT_EXPIRED"
+	ErrDiscountUsageLimitReached   ErrorCode = "DISCOUNT_USAGE_LIMIT_REACHED"
+	ErrDiscountMinAmountNotReached ErrorCode = "DISCOUNT_MIN_AMOUNT_NOT_REACHED"
+
+	ErrInvalidIdempotencyKey     ErrorCode = "INVALID_IDEMPOTENCY_KEY"
+	ErrIdempotencyKeyInProgress  ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	ErrIdempotencyKeyReused      ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	ErrReservationNotFound       ErrorCode = "RESERVATION_NOT_FOUND"
+	ErrInvalidReservationTTL     ErrorCode = "INVALID_RESERVATION_TTL"
+	ErrRedemptionNotFound        ErrorCode = "REDEMPTION_NOT_FOUND"
+	ErrRedemptionAlreadyReversed ErrorCode = "REDEMPTION_ALREADY_REVERSED"
+	ErrNoUsageToRestore          ErrorCode = "NO_USAGE_TO_RESTORE"
+
+	ErrGiftBalanceMode         ErrorCode = "GIFT_BALANCE_MODE"
+	ErrGiftNotBalanceMode      ErrorCode = "GIFT_NOT_BALANCE_MODE"
+	ErrGiftBalanceUsedUp       ErrorCode = "GIFT_BALANCE_USED_UP"
+	ErrInsufficientGiftBalance ErrorCode = "INSUFFICIENT_GIFT_BALANCE"
+
+	ErrDuplicateCode      ErrorCode = "DUPLICATE_CODE"
+	ErrCodeNotStackable   ErrorCode = "CODE_NOT_STACKABLE"
+	ErrOrderAmountCovered ErrorCode = "ORDER_AMOUNT_COVERED"
+
+	ErrCampaignNotFound     ErrorCode = "CAMPAIGN_NOT_FOUND"
+	ErrCampaignNotStarted   ErrorCode = "CAMPAIGN_NOT_STARTED"
+	ErrCampaignEnded        ErrorCode = "CAMPAIGN_ENDED"
+	ErrCampaignBudgetUsedUp ErrorCode = "CAMPAIGN_BUDGET_USED_UP"
+	ErrInvalidCampaign      ErrorCode = "INVALID_CAMPAIGN"
+
+	ErrJobNotFound    ErrorCode = "JOB_NOT_FOUND"
+	ErrJobNotFinished ErrorCode = "JOB_NOT_FINISHED"
+	ErrInvalidJob     ErrorCode = "INVALID_JOB"
+
+	ErrInvalidCheckCharacter ErrorCode = "INVALID_CHECK_CHARACTER"
+	ErrCodeGenerationFailed  ErrorCode = "CODE_GENERATION_FAILED"
+	ErrInvalidPattern        ErrorCode = "INVALID_PATTERN"
+	ErrPatternExhausted      ErrorCode = "PATTERN_EXHAUSTED"
+	ErrCodeAlreadyExists     ErrorCode = "CODE_ALREADY_EXISTS"
+
+	ErrInvalidImport       ErrorCode = "INVALID_IMPORT"
+	ErrInvalidImportRecord ErrorCode = "INVALID_IMPORT_RECORD"
+
+	ErrInvalidFilter ErrorCode = "INVALID_FILTER"
+
+	ErrInvalidCondition         ErrorCode = "INVALID_CONDITION"
+	ErrDiscountConditionsNotMet ErrorCode = "DISCOUNT_CONDITIONS_NOT_MET"
+
+	ErrInvalidSchedule       ErrorCode = "INVALID_SCHEDULE"
+	ErrGiftOutOfSchedule     ErrorCode = "GIFT_OUT_OF_SCHEDULE"
+	ErrDiscountOutOfSchedule ErrorCode = "DISCOUNT_OUT_OF_SCHEDULE"
+
+	ErrInvalidDate ErrorCode = "INVALID_DATE"
+
+	ErrInvalidStatus           ErrorCode = "INVALID_STATUS"
+	ErrInvalidStatusTransition ErrorCode = "INVALID_STATUS_TRANSITION"
+	ErrGiftPaused              ErrorCode = "GIFT_PAUSED"
+	ErrGiftUnavailable         ErrorCode = "GIFT_UNAVAILABLE"
+	ErrDiscountPaused          ErrorCode = "DISCOUNT_PAUSED"
+	ErrDiscountUnavailable     ErrorCode = "DISCOUNT_UNAVAILABLE"
+
+	ErrDeletedCodeNotFound ErrorCode = "DELETED_CODE_NOT_FOUND"
+
+	ErrInvalidVersion  ErrorCode = "INVALID_VERSION"
+	ErrVersionMismatch ErrorCode = "VERSION_MISMATCH"
 )
 
 type ServiceError struct {
@@ -42,6 +112,24 @@ func ValidationErr(method, message string, code ErrorCode) error {
 	}
 }
 
+func ConflictErr(method, message string, code ErrorCode) error {
+	return &ServiceError{
+		Method:    method,
+		Message:   message,
+		Code:      http.StatusConflict,
+		ErrorCode: code,
+	}
+}
+
+func PreconditionFailedErr(method, message string, code ErrorCode) error {
+	return &ServiceError{
+		Method:    method,
+		Message:   message,
+		Code:      http.StatusPreconditionFailed,
+		ErrorCode: code,
+	}
+}
+
 func DBError(method, repo string, cause error) error {
 	err := &ServiceError{
 		Method: fmt.Sprintf("%s.%s", repo, method),
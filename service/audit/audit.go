@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"discount/internal/date"
+	"discount/internal/serr"
+	"discount/storage/audit"
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionRestore    = "restore"
+	ActionPause      = "pause"
+	ActionResume     = "resume"
+	ActionArchive    = "archive"
+	ActionBulkCreate = "bulk_create"
+	ActionImport     = "import"
+)
+
+// Entities whose changes are recorded in the audit log.
+const (
+	EntityGift     = "gift"
+	EntityDiscount = "discount"
+	EntityCampaign = "campaign"
+	EntityJob      = "job"
+)
+
+// Actor is who makes a change: the authenticated principal or the actor named by the request, together with the
+// trace ID of the request.
+type Actor struct {
+	ID      string
+	TraceID string
+}
+
+type DTO struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entityId"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+	TraceID   string          `json:"traceId"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+type ListDTO struct {
+	Items    []*DTO `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+// ListRequest filters the audit log. From and To are dates, both inclusive, bounding when the changes were made.
+type ListRequest struct {
+	Actor    string `form:"actor"`
+	Action   string `form:"action"`
+	Entity   string `form:"entity"`
+	EntityID string `form:"entityId"`
+	TraceID  string `form:"traceId"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+}
+
+// Record adds a change made by the actor to the audit log. Before and After are the entity around the change and
+// are nil for creations and deletions respectively. Changes made in a database transaction are recorded through
+// a service bound to it with WithTX, so the change and its entry are committed together.
+func (s *Service) Record(actor Actor, action, entity, entityID string, before, after any) error {
+	e := &audit.Entry{
+		Actor:    actor.ID,
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		TraceID:  actor.TraceID,
+	}
+	var err error
+	if e.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if e.After, err = snapshot(after); err != nil {
+		return err
+	}
+	return s.audit.Create(e)
+}
+
+// List returns the audit entries matching the request page by page, newest first.
+func (s *Service) List(r *ListRequest, page, pageSize int) (*ListDTO, error) {
+	f := &audit.Filter{Actor: r.Actor, Action: r.Action, Entity: r.Entity, EntityID: r.EntityID, TraceID: r.TraceID}
+	var err error
+	if r.From != "" {
+		f.From, err = date.ParseDay(r.From)
+		if err != nil {
+			return nil, serr.ValidationErr("from", "invalid date", serr.ErrInvalidFilter)
+		}
+	}
+	if r.To != "" {
+		f.To, err = date.ParseDay(r.To)
+		if err != nil {
+			return nil, serr.ValidationErr("to", "invalid date", serr.ErrInvalidFilter)
+		}
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+	entries, total, err := s.audit.GetAll(f, pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &ListDTO{Items: make([]*DTO, 0, len(entries)), Total: total, Page: page, PageSize: pageSize}
+	for _, e := range entries {
+		result.Items = append(result.Items, s.FromDBModel(e))
+	}
+	return result, nil
+}
+
+// snapshot encodes an entity as JSON, or returns nil when there is no entity.
+func snapshot(v any) (audit.Snapshot, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
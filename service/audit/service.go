@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"database/sql"
+	"discount/storage/audit"
+	"encoding/json"
+)
+
+type Service struct {
+	audit audit.Storage
+}
+
+func New(audit audit.Storage) *Service {
+	return &Service{
+		audit: audit,
+	}
+}
+
+// WithTX returns a service that records the entries in the given transaction.
+func (s *Service) WithTX(tx *sql.Tx) (*Service, error) {
+	service := *s
+	a, err := s.audit.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.audit = a
+	return &service, nil
+}
+
+func (s *Service) FromDBModel(e *audit.Entry) *DTO {
+	return &DTO{
+		ID:        e.ID,
+		Actor:     e.Actor,
+		Action:    e.Action,
+		Entity:    e.Entity,
+		EntityID:  e.EntityID,
+		Before:    json.RawMessage(e.Before),
+		After:     json.RawMessage(e.After),
+		TraceID:   e.TraceID,
+		CreatedAt: e.CreatedAt,
+	}
+}
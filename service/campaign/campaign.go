@@ -0,0 +1,141 @@
+package campaign
+
+import (
+	"discount/internal/serr"
+	"discount/service/audit"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DTO struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	StartDateTime  time.Time `json:"startDateTime"`
+	ExpirationDate time.Time `json:"expirationDate"`
+	Budget         int64     `json:"budget"`
+	Spent          int64     `json:"spent"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// CreateRequest describes a campaign. A zero Budget means the codes of the campaign are not limited by a budget.
+type CreateRequest struct {
+	Name           string `json:"name"`
+	StartDateTime  string `json:"startDateTime"`
+	ExpirationDate string `json:"expirationDate"`
+	Budget         int64  `json:"budget"`
+}
+
+type ListDTO struct {
+	Items    []*DTO `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+func (s *Service) Create(actor audit.Actor, r *CreateRequest) (*DTO, error) {
+	err := validate(r)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.FromCreateRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		err := service.campaign.Create(c)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(c)
+		return service.audit.Record(actor, audit.ActionCreate, audit.EntityCampaign, entityID(c.ID), nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) GetByID(id int64) (*DTO, error) {
+	c, err := s.campaign.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.FromDBModel(c), nil
+}
+
+func (s *Service) List(page, pageSize int) (*ListDTO, error) {
+	campaigns, total, err := s.campaign.GetAllByPage(pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &ListDTO{Items: make([]*DTO, 0, len(campaigns)), Total: total, Page: page, PageSize: pageSize}
+	for _, c := range campaigns {
+		result.Items = append(result.Items, s.FromDBModel(c))
+	}
+	return result, nil
+}
+
+// Update changes the name, date window and budget of a campaign. What the campaign has spent so far is kept.
+func (s *Service) Update(actor audit.Actor, id int64, r *CreateRequest) (*DTO, error) {
+	err := validate(r)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.campaign.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.FromCreateRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	c.ID = id
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		err := service.campaign.Update(c)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(c)
+		return service.audit.Record(actor, audit.ActionUpdate, audit.EntityCampaign, entityID(id),
+			s.FromDBModel(current), result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete removes a campaign. Its gifts and discounts are kept and no longer belong to any campaign.
+func (s *Service) Delete(actor audit.Actor, id int64) error {
+	current, err := s.campaign.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return s.transaction(func(service *Service) error {
+		err := service.campaign.Delete(id)
+		if err != nil {
+			return err
+		}
+		return service.audit.Record(actor, audit.ActionDelete, audit.EntityCampaign, entityID(id),
+			s.FromDBModel(current), nil)
+	})
+}
+
+// entityID is how a campaign is identified in the audit log.
+func entityID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func validate(r *CreateRequest) error {
+	if strings.TrimSpace(r.Name) == "" {
+		return serr.ValidationErr("name", "campaign name is required", serr.ErrInvalidCampaign)
+	}
+	if r.Budget < 0 {
+		return serr.ValidationErr("budget", "invalid campaign budget", serr.ErrInvalidCampaign)
+	}
+	return nil
+}
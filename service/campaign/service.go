@@ -0,0 +1,81 @@
+package campaign
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/date"
+	"discount/service/audit"
+	"discount/storage/campaign"
+)
+
+type Service struct {
+	campaign campaign.Storage
+	audit    *audit.Service
+}
+
+func New(campaign campaign.Storage, audit *audit.Service) *Service {
+	return &Service{
+		campaign: campaign,
+		audit:    audit,
+	}
+}
+
+func (s *Service) withTX(tx *sql.Tx) (*Service, error) {
+	service := *s
+	c, err := s.campaign.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.campaign = c
+	a, err := s.audit.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.audit = a
+	return &service, nil
+}
+
+// transaction runs fn on a service bound to a new database transaction, so a change and its audit entry are
+// committed together.
+func (s *Service) transaction(fn func(service *Service) error) error {
+	return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		service, err := s.withTX(tx)
+		if err != nil {
+			return err
+		}
+		return fn(service)
+	})
+}
+
+func (s *Service) FromDBModel(c *campaign.Campaign) *DTO {
+	return &DTO{
+		ID:             c.ID,
+		Name:           c.Name,
+		StartDateTime:  c.StartDateTime,
+		ExpirationDate: c.ExpirationDate,
+		Budget:         c.Budget,
+		Spent:          c.Spent,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+}
+
+// FromCreateRequest returns the campaign described by the request. Dates are RFC3339 timestamps or dates in the
+// business timezone; a malformed date is a validation error.
+func (s *Service) FromCreateRequest(r *CreateRequest) (*campaign.Campaign, error) {
+	stDate, err := date.Parse("startDateTime", r.StartDateTime)
+	if err != nil {
+		return nil, err
+	}
+	exDate, err := date.Parse("expirationDate", r.ExpirationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign.Campaign{
+		Name:           r.Name,
+		StartDateTime:  stDate,
+		ExpirationDate: exDate,
+		Budget:         r.Budget,
+	}, nil
+}
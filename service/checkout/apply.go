@@ -0,0 +1,227 @@
+package checkout
+
+import (
+	"discount/internal/serr"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"time"
+)
+
+// ApplyRequest lists the codes a customer wants to combine on one order. The conditions of discounts are checked
+// against Cart.
+type ApplyRequest struct {
+	Codes  []string             `json:"codes"`
+	Amount int64                `json:"amount"`
+	Cart   discountStorage.Cart `json:"cart" swaggertype:"object"`
+}
+
+type AppliedCodeDTO struct {
+	Code           string `json:"code"`
+	Type           string `json:"type"`
+	DiscountAmount int64  `json:"discountAmount"`
+}
+
+// RejectedCodeDTO is a code left out of the combination. Failure holds the reason, and FailedConditions the
+// conditions of a discount the cart does not meet.
+type RejectedCodeDTO struct {
+	Code             string                     `json:"code"`
+	Type             string                     `json:"type"`
+	FailedConditions discountStorage.Conditions `json:"failedConditions,omitempty" swaggertype:"array,object"`
+	Failure          *serr.ServiceError         `json:"-"`
+}
+
+// ApplyDTO is the combination of codes that can be used together on an order and the resulting price.
+type ApplyDTO struct {
+	OrderAmount    int64              `json:"orderAmount"`
+	DiscountAmount int64              `json:"discountAmount"`
+	FinalAmount    int64              `json:"finalAmount"`
+	Applied        []*AppliedCodeDTO  `json:"applied"`
+	Rejected       []*RejectedCodeDTO `json:"rejected"`
+}
+
+// candidate is a resolved code together with its stacking rules.
+type candidate struct {
+	code                  string
+	codeType              string
+	exclusive             bool
+	stackableWithGift     bool
+	stackableWithDiscount bool
+	campaignID            *int64
+	gift                  *giftStorage.Gift
+	discount              *discountStorage.Discount
+}
+
+// Apply works out which of the given codes can be used together on an order and what the order costs with them,
+// without consuming any of them. Discounts are applied before gifts, so percentages are taken off the order
+// amount and gifts pay for what is left. Within each kind the codes keep the requested order, and a code that
+// cannot be combined with the codes already accepted is rejected.
+func (s *Service) Apply(r *ApplyRequest) (*ApplyDTO, error) {
+	now := time.Now()
+	return apply(r, s.candidate, func(id *int64, amount int64) error {
+		return s.checkCampaign(id, amount, now)
+	}, now)
+}
+
+// apply is Apply with the lookup of codes and the check of campaign budgets passed in.
+func apply(
+	r *ApplyRequest,
+	lookup func(code string) (*candidate, error),
+	checkCampaign func(id *int64, amount int64) error,
+	now time.Time,
+) (*ApplyDTO, error) {
+	if r.Amount <= 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	result := &ApplyDTO{
+		OrderAmount: r.Amount,
+		FinalAmount: r.Amount,
+		Applied:     make([]*AppliedCodeDTO, 0),
+		Rejected:    make([]*RejectedCodeDTO, 0),
+	}
+
+	var discounts, gifts []*candidate
+	seen := make(map[string]bool, len(r.Codes))
+	for _, code := range r.Codes {
+		if seen[code] {
+			result.reject(&candidate{code: code},
+				serr.ValidationErr("code", "code is given more than once", serr.ErrDuplicateCode))
+			continue
+		}
+		seen[code] = true
+		c, err := lookup(code)
+		if err != nil {
+			if err = result.fail(&candidate{code: code}, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if c.codeType == TypeDiscount {
+			discounts = append(discounts, c)
+		} else {
+			gifts = append(gifts, c)
+		}
+	}
+
+	accepted := make([]*candidate, 0, len(discounts)+len(gifts))
+	// spent sums up what the accepted codes take from each campaign budget.
+	spent := make(map[int64]int64)
+	for _, c := range append(discounts, gifts...) {
+		if !compatibleWith(c, accepted) {
+			result.reject(c, serr.ValidationErr("code", "code cannot be combined with the other codes",
+				serr.ErrCodeNotStackable))
+			continue
+		}
+		if result.FinalAmount == 0 {
+			result.reject(c, serr.ValidationErr("code", "order amount is already covered",
+				serr.ErrOrderAmountCovered))
+			continue
+		}
+		off, err := c.calculate(result.FinalAmount, &r.Cart, now)
+		if err == nil && c.campaignID != nil {
+			err = checkCampaign(c.campaignID, spent[*c.campaignID]+off)
+		}
+		if err != nil {
+			conditionsNotMet := isErrorCode(err, serr.ErrDiscountConditionsNotMet)
+			if err = result.fail(c, err); err != nil {
+				return nil, err
+			}
+			if conditionsNotMet {
+				result.Rejected[len(result.Rejected)-1].FailedConditions = c.discount.Conditions.Failed(&r.Cart)
+			}
+			continue
+		}
+		accepted = append(accepted, c)
+		if c.campaignID != nil {
+			spent[*c.campaignID] += off
+		}
+		result.Applied = append(result.Applied, &AppliedCodeDTO{Code: c.code, Type: c.codeType, DiscountAmount: off})
+		result.DiscountAmount += off
+		result.FinalAmount -= off
+	}
+	return result, nil
+}
+
+func (s *Service) candidate(code string) (*candidate, error) {
+	g, d, err := s.resolve(code, "")
+	if err != nil {
+		return nil, err
+	}
+	return newCandidate(code, g, d), nil
+}
+
+// newCandidate returns the candidate of a code resolved to either g or d.
+func newCandidate(code string, g *giftStorage.Gift, d *discountStorage.Discount) *candidate {
+	if g != nil {
+		return &candidate{
+			code:                  code,
+			codeType:              TypeGift,
+			exclusive:             g.Exclusive,
+			stackableWithGift:     g.StackableWithGift,
+			stackableWithDiscount: g.StackableWithDiscount,
+			campaignID:            g.CampaignID,
+			gift:                  g,
+		}
+	}
+	return &candidate{
+		code:                  code,
+		codeType:              TypeDiscount,
+		exclusive:             d.Exclusive,
+		stackableWithGift:     d.StackableWithGift,
+		stackableWithDiscount: d.StackableWithDiscount,
+		campaignID:            d.CampaignID,
+		discount:              d,
+	}
+}
+
+// calculate returns the amount the code takes off an order of the given amount and cart.
+func (c *candidate) calculate(amount int64, cart *discountStorage.Cart, now time.Time) (int64, error) {
+	if c.gift != nil {
+		price, err := giftService.Calculate(c.gift, amount, now)
+		if err != nil {
+			return 0, err
+		}
+		return price.DiscountAmount, nil
+	}
+	price, err := discountService.Calculate(c.discount, amount, cart, now)
+	if err != nil {
+		return 0, err
+	}
+	return price.DiscountAmount, nil
+}
+
+// allows reports whether the code can be used together with other.
+func (c *candidate) allows(other *candidate) bool {
+	if c.exclusive {
+		return false
+	}
+	if other.codeType == TypeGift {
+		return c.stackableWithGift
+	}
+	return c.stackableWithDiscount
+}
+
+// compatibleWith reports whether c and every accepted code allow each other.
+func compatibleWith(c *candidate, accepted []*candidate) bool {
+	for _, a := range accepted {
+		if !c.allows(a) || !a.allows(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *ApplyDTO) reject(c *candidate, err error) {
+	_ = a.fail(c, err)
+}
+
+// fail records a validation error as the reason the code is rejected. Any other error is returned as is.
+func (a *ApplyDTO) fail(c *candidate, err error) error {
+	f, err := failure(err)
+	if err != nil {
+		return err
+	}
+	a.Rejected = append(a.Rejected, &RejectedCodeDTO{Code: c.code, Type: c.codeType, Failure: f})
+	return nil
+}
@@ -0,0 +1,165 @@
+package checkout
+
+import (
+	"discount/internal/serr"
+	"discount/storage/campaign"
+	"discount/storage/discount"
+	"discount/storage/gift"
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	campaignID := int64(1)
+	gifts := map[string]*gift.Gift{
+		"GIFT":      {Code: "GIFT", GiftAmount: 30000, StackableWithGift: true, StackableWithDiscount: true},
+		"BIG_GIFT":  {Code: "BIG_GIFT", GiftAmount: 200000, StackableWithGift: true, StackableWithDiscount: true},
+		"GIFT_ONLY": {Code: "GIFT_ONLY", GiftAmount: 30000, StackableWithGift: true},
+		"CAMPAIGN_A": {Code: "CAMPAIGN_A", GiftAmount: 30000, StackableWithGift: true,
+			CampaignID: &campaignID},
+		"CAMPAIGN_B": {Code: "CAMPAIGN_B", GiftAmount: 30000, StackableWithGift: true,
+			CampaignID: &campaignID},
+	}
+	discounts := map[string]*discount.Discount{
+		"PERCENT": {Code: "PERCENT", PercentOff: 20, StackableWithGift: true, StackableWithDiscount: true},
+		"FIXED":   {Code: "FIXED", DiscountAmount: 5000, StackableWithGift: true, StackableWithDiscount: true},
+		"ALONE":   {Code: "ALONE", PercentOff: 10},
+		"EXCLUSIVE": {Code: "EXCLUSIVE", PercentOff: 50, Exclusive: true, StackableWithGift: true,
+			StackableWithDiscount: true},
+	}
+	campaigns := map[int64]*campaign.Campaign{
+		campaignID: {ID: campaignID, Budget: 50000},
+	}
+	lookup := func(code string) (*candidate, error) {
+		if g, ok := gifts[code]; ok {
+			return newCandidate(code, g, nil), nil
+		}
+		if d, ok := discounts[code]; ok {
+			return newCandidate(code, nil, d), nil
+		}
+		return nil, serr.ValidationErr("code", "unknown code", serr.ErrUnknownCode)
+	}
+	checkCampaign := func(id *int64, amount int64) error {
+		if id == nil {
+			return nil
+		}
+		return campaigns[*id].Validate(now, amount)
+	}
+
+	type rejected struct {
+		code      string
+		errorCode serr.ErrorCode
+	}
+	tests := []struct {
+		name         string
+		codes        []string
+		wantApplied  []string
+		wantRejected []rejected
+		wantFinal    int64
+	}{
+		{
+			name:         "exclusive code first",
+			codes:        []string{"EXCLUSIVE", "PERCENT"},
+			wantApplied:  []string{"EXCLUSIVE"},
+			wantRejected: []rejected{{"PERCENT", serr.ErrCodeNotStackable}},
+			wantFinal:    50000,
+		},
+		{
+			name:         "exclusive code last",
+			codes:        []string{"PERCENT", "EXCLUSIVE"},
+			wantApplied:  []string{"PERCENT"},
+			wantRejected: []rejected{{"EXCLUSIVE", serr.ErrCodeNotStackable}},
+			wantFinal:    80000,
+		},
+		{
+			name:        "discount applied before gift",
+			codes:       []string{"GIFT", "PERCENT"},
+			wantApplied: []string{"PERCENT", "GIFT"},
+			wantFinal:   50000,
+		},
+		{
+			name:         "gift not stackable with discount",
+			codes:        []string{"PERCENT", "GIFT_ONLY"},
+			wantApplied:  []string{"PERCENT"},
+			wantRejected: []rejected{{"GIFT_ONLY", serr.ErrCodeNotStackable}},
+			wantFinal:    80000,
+		},
+		{
+			name:        "discounts in requested order",
+			codes:       []string{"FIXED", "PERCENT"},
+			wantApplied: []string{"FIXED", "PERCENT"},
+			wantFinal:   76000,
+		},
+		{
+			name:         "discount not stackable with discount",
+			codes:        []string{"ALONE", "PERCENT"},
+			wantApplied:  []string{"ALONE"},
+			wantRejected: []rejected{{"PERCENT", serr.ErrCodeNotStackable}},
+			wantFinal:    90000,
+		},
+		{
+			name:         "duplicate code",
+			codes:        []string{"PERCENT", "PERCENT"},
+			wantApplied:  []string{"PERCENT"},
+			wantRejected: []rejected{{"PERCENT", serr.ErrDuplicateCode}},
+			wantFinal:    80000,
+		},
+		{
+			name:         "order amount already covered",
+			codes:        []string{"BIG_GIFT", "GIFT"},
+			wantApplied:  []string{"BIG_GIFT"},
+			wantRejected: []rejected{{"GIFT", serr.ErrOrderAmountCovered}},
+			wantFinal:    0,
+		},
+		{
+			name:         "campaign budget shared by two codes",
+			codes:        []string{"CAMPAIGN_A", "CAMPAIGN_B"},
+			wantApplied:  []string{"CAMPAIGN_A"},
+			wantRejected: []rejected{{"CAMPAIGN_B", serr.ErrCampaignBudgetUsedUp}},
+			wantFinal:    70000,
+		},
+		{
+			name:         "unknown code",
+			codes:        []string{"UNKNOWN", "GIFT"},
+			wantApplied:  []string{"GIFT"},
+			wantRejected: []rejected{{"UNKNOWN", serr.ErrUnknownCode}},
+			wantFinal:    70000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ApplyRequest{Codes: tt.codes, Amount: 100000}
+			result, err := apply(r, lookup, checkCampaign, now)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(result.Applied) != len(tt.wantApplied) {
+				t.Fatalf("expected applied %v, got %d codes", tt.wantApplied, len(result.Applied))
+			}
+			for i, code := range tt.wantApplied {
+				if result.Applied[i].Code != code {
+					t.Fatalf("expected applied code %d to be %v, got %v", i, code, result.Applied[i].Code)
+				}
+			}
+			if len(result.Rejected) != len(tt.wantRejected) {
+				t.Fatalf("expected rejected %v, got %d codes", tt.wantRejected, len(result.Rejected))
+			}
+			for i, want := range tt.wantRejected {
+				got := result.Rejected[i]
+				if got.Code != want.code || got.Failure == nil || got.Failure.ErrorCode != want.errorCode {
+					t.Fatalf("expected %v rejected with %v, got %v with %v", want.code, want.errorCode, got.Code,
+						got.Failure)
+				}
+			}
+			if result.FinalAmount != tt.wantFinal {
+				t.Fatalf("expected final amount %v, got %v", tt.wantFinal, result.FinalAmount)
+			}
+			if result.DiscountAmount != result.OrderAmount-result.FinalAmount {
+				t.Fatalf("expected discount amount %v, got %v", result.OrderAmount-result.FinalAmount,
+					result.DiscountAmount)
+			}
+		})
+	}
+}
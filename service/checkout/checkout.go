@@ -0,0 +1,158 @@
+package checkout
+
+import (
+	"discount/internal/serr"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	TypeGift     = "gift"
+	TypeDiscount = "discount"
+)
+
+// QuoteRequest describes the order a code is quoted for. The conditions of a discount are checked against Cart.
+type QuoteRequest struct {
+	Code   string               `json:"code"`
+	Type   string               `json:"type"`
+	Amount int64                `json:"amount"`
+	Cart   discountStorage.Cart `json:"cart" swaggertype:"object"`
+}
+
+// QuoteDTO describes what an order would cost with a code applied. When the code cannot be used, Failure holds the
+// reason, DiscountAmount is zero and FinalAmount equals OrderAmount. FailedConditions lists the conditions of a
+// discount the cart does not meet.
+type QuoteDTO struct {
+	Code             string                     `json:"code"`
+	Type             string                     `json:"type"`
+	OrderAmount      int64                      `json:"orderAmount"`
+	DiscountAmount   int64                      `json:"discountAmount"`
+	FinalAmount      int64                      `json:"finalAmount"`
+	RemainingUsages  *int64                     `json:"remainingUsages"`
+	FailedConditions discountStorage.Conditions `json:"failedConditions,omitempty" swaggertype:"array,object"`
+	Failure          *serr.ServiceError         `json:"-"`
+}
+
+// Quote calculates the price of an order with the given code applied without consuming a usage of the code.
+// Type may be left empty, in which case the code is looked up among gifts first and then among discounts.
+func (s *Service) Quote(r *QuoteRequest) (*QuoteDTO, error) {
+	if r.Amount <= 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	q := &QuoteDTO{Code: r.Code, Type: r.Type, OrderAmount: r.Amount, FinalAmount: r.Amount}
+	now := time.Now()
+
+	g, d, err := s.resolve(r.Code, r.Type)
+	if err != nil {
+		return q.fail(err)
+	}
+
+	if g != nil {
+		q.Type = TypeGift
+		q.RemainingUsages = remainingUsages(g.UsageLimit, g.UsedCount)
+		price, err := giftService.Calculate(g, r.Amount, now)
+		if err == nil {
+			err = s.checkCampaign(g.CampaignID, price.DiscountAmount, now)
+		}
+		if err != nil {
+			return q.fail(err)
+		}
+		q.DiscountAmount, q.FinalAmount = price.DiscountAmount, price.FinalAmount
+		return q, nil
+	}
+
+	q.Type = TypeDiscount
+	q.RemainingUsages = remainingUsages(d.UsageLimit, d.UsedCount)
+	price, err := discountService.Calculate(d, r.Amount, &r.Cart, now)
+	if err == nil {
+		err = s.checkCampaign(d.CampaignID, price.DiscountAmount, now)
+	}
+	if err != nil {
+		if isErrorCode(err, serr.ErrDiscountConditionsNotMet) {
+			q.FailedConditions = d.Conditions.Failed(&r.Cart)
+		}
+		return q.fail(err)
+	}
+	q.DiscountAmount, q.FinalAmount = price.DiscountAmount, price.FinalAmount
+	return q, nil
+}
+
+// resolve looks the code up as a gift or a discount. Exactly one of the returned records is set when err is nil.
+// A mistyped generated code is rejected before it is looked up.
+func (s *Service) resolve(code, codeType string) (*giftStorage.Gift, *discountStorage.Discount, error) {
+	if err := s.codes.Check(code); err != nil {
+		return nil, nil, err
+	}
+	switch codeType {
+	case TypeGift:
+		g, err := s.gift.GetByCode(code)
+		return g, nil, err
+	case TypeDiscount:
+		d, err := s.discount.GetByCode(code)
+		return nil, d, err
+	case "":
+		g, err := s.gift.GetByCode(code)
+		if !isErrorCode(err, serr.ErrInvalidGiftCode) {
+			return g, nil, err
+		}
+		d, err := s.discount.GetByCode(code)
+		if !isErrorCode(err, serr.ErrInvalidDiscountCode) {
+			return nil, d, err
+		}
+		return nil, nil, serr.ValidationErr("code", "unknown code", serr.ErrUnknownCode)
+	default:
+		return nil, nil, serr.ValidationErr("type", "invalid code type", serr.ErrInvalidCodeType)
+	}
+}
+
+// checkCampaign checks that the campaign a code belongs to, if any, can pay amount at the given time.
+func (s *Service) checkCampaign(id *int64, amount int64, now time.Time) error {
+	if id == nil {
+		return nil
+	}
+	c, err := s.campaign.GetByID(*id)
+	if err != nil {
+		return err
+	}
+	return c.Validate(now, amount)
+}
+
+// fail records a validation error as the reason the code cannot be used. Any other error is returned as is.
+func (q *QuoteDTO) fail(err error) (*QuoteDTO, error) {
+	f, err := failure(err)
+	if err != nil {
+		return nil, err
+	}
+	q.Failure = f
+	return q, nil
+}
+
+// failure separates validation errors, which explain why a code cannot be used, from any other error.
+func failure(err error) (*serr.ServiceError, error) {
+	var e *serr.ServiceError
+	if errors.As(err, &e) && e.Code == http.StatusBadRequest && e.ErrorCode != serr.ErrInvalidCodeType {
+		return e, nil
+	}
+	return nil, err
+}
+
+func isErrorCode(err error, code serr.ErrorCode) bool {
+	var e *serr.ServiceError
+	return errors.As(err, &e) && e.ErrorCode == code
+}
+
+func remainingUsages(limit, used int64) *int64 {
+	if limit <= 0 {
+		return nil
+	}
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
@@ -0,0 +1,116 @@
+package checkout
+
+import (
+	"discount/internal/serr"
+	discountService "discount/service/discount"
+	"time"
+)
+
+// Reason summarizes why a code is or is not eligible for use.
+type Reason string
+
+const (
+	ReasonValid          Reason = "valid"
+	ReasonUnknown        Reason = "unknown"
+	ReasonNotStarted     Reason = "not_started"
+	ReasonExpired        Reason = "expired"
+	ReasonExhausted      Reason = "exhausted"
+	ReasonPaused         Reason = "paused"
+	ReasonOutOfSchedule  Reason = "out_of_schedule"
+	ReasonBelowMinAmount Reason = "below_min_amount"
+	ReasonIneligible     Reason = "ineligible"
+)
+
+var reasons = map[serr.ErrorCode]Reason{
+	serr.ErrUnknownCode:                 ReasonUnknown,
+	serr.ErrInvalidGiftCode:             ReasonUnknown,
+	serr.ErrInvalidDiscountCode:         ReasonUnknown,
+	serr.ErrInvalidCheckCharacter:       ReasonUnknown,
+	serr.ErrGiftNotStarted:              ReasonNotStarted,
+	serr.ErrDiscountNotStarted:          ReasonNotStarted,
+	serr.ErrGiftExpired:                 ReasonExpired,
+	serr.ErrDiscountExpired:             ReasonExpired,
+	serr.ErrGiftUsageLimitReached:       ReasonExhausted,
+	serr.ErrDiscountUsageLimitReached:   ReasonExhausted,
+	serr.ErrGiftBalanceUsedUp:           ReasonExhausted,
+	serr.ErrGiftPaused:                  ReasonPaused,
+	serr.ErrDiscountPaused:              ReasonPaused,
+	serr.ErrGiftOutOfSchedule:           ReasonOutOfSchedule,
+	serr.ErrDiscountOutOfSchedule:       ReasonOutOfSchedule,
+	serr.ErrDiscountMinAmountNotReached: ReasonBelowMinAmount,
+	serr.ErrCampaignNotStarted:          ReasonNotStarted,
+	serr.ErrCampaignEnded:               ReasonExpired,
+	serr.ErrCampaignBudgetUsedUp:        ReasonExhausted,
+}
+
+type EligibilityRequest struct {
+	Code   string `form:"-"`
+	Type   string `form:"type"`
+	Amount int64  `form:"amount"`
+}
+
+// EligibilityDTO is the verdict on whether a code can be used right now. Failure holds the error explaining
+// the verdict when the code is not eligible.
+type EligibilityDTO struct {
+	Code     string             `json:"code"`
+	Type     string             `json:"type"`
+	Eligible bool               `json:"eligible"`
+	Reason   Reason             `json:"reason"`
+	Failure  *serr.ServiceError `json:"-"`
+}
+
+// Eligibility explains whether the code can be used right now and, if not, why.
+// The minimum order amount of a discount is only checked when an amount is given. The conditions of a discount
+// are not checked, as they depend on the cart; Quote checks them.
+func (s *Service) Eligibility(r *EligibilityRequest) (*EligibilityDTO, error) {
+	if r.Amount < 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	e := &EligibilityDTO{Code: r.Code, Type: r.Type}
+	now := time.Now()
+
+	g, d, err := s.resolve(r.Code, r.Type)
+	switch {
+	case err != nil:
+	case g != nil:
+		e.Type = TypeGift
+		err = g.Validate(now)
+		if err == nil {
+			err = s.checkCampaign(g.CampaignID, 0, now)
+		}
+	default:
+		e.Type = TypeDiscount
+		var off int64
+		if r.Amount > 0 {
+			var price *discountService.PriceDTO
+			price, err = discountService.Calculate(d, r.Amount, nil, now)
+			if price != nil {
+				off = price.DiscountAmount
+			}
+		} else {
+			err = d.Validate(now)
+		}
+		if err == nil {
+			err = s.checkCampaign(d.CampaignID, off, now)
+		}
+	}
+	return e.verdict(err)
+}
+
+func (e *EligibilityDTO) verdict(err error) (*EligibilityDTO, error) {
+	if err == nil {
+		e.Eligible = true
+		e.Reason = ReasonValid
+		return e, nil
+	}
+	f, err := failure(err)
+	if err != nil {
+		return nil, err
+	}
+	e.Failure = f
+	e.Reason = ReasonIneligible
+	if r, ok := reasons[e.Failure.ErrorCode]; ok {
+		e.Reason = r
+	}
+	return e, nil
+}
@@ -0,0 +1,29 @@
+package checkout
+
+import (
+	"discount/internal/codegen"
+	campaignStorage "discount/storage/campaign"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+)
+
+type Service struct {
+	gift     giftStorage.Storage
+	discount discountStorage.Storage
+	campaign campaignStorage.Storage
+	codes    *codegen.Generator
+}
+
+func New(
+	gift giftStorage.Storage,
+	discount discountStorage.Storage,
+	campaign campaignStorage.Storage,
+	codes *codegen.Generator,
+) *Service {
+	return &Service{
+		gift:     gift,
+		discount: discount,
+		campaign: campaign,
+		codes:    codes,
+	}
+}
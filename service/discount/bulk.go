@@ -0,0 +1,63 @@
+package discount
+
+import (
+	"discount/internal/codegen"
+	"discount/storage/discount"
+)
+
+// ValidateTemplate checks a create request used as the template of a bulk generation job for count discounts
+// before any discount is created from it.
+func (s *Service) ValidateTemplate(r *CreateRequest, count int64) error {
+	_, err := s.FromCreateRequest(r)
+	if err != nil {
+		return err
+	}
+	err = r.Conditions.Validate()
+	if err != nil {
+		return err
+	}
+	err = r.Schedule.Validate()
+	if err != nil {
+		return err
+	}
+	err = s.ensureCampaignExists(r.CampaignID)
+	if err != nil {
+		return err
+	}
+	_, err = s.codePattern(r, count)
+	return err
+}
+
+// CreateBatch creates up to n discounts from the template for a bulk generation job, each with a newly generated
+// code, and returns how many were created. Codes that turn out to be taken are skipped, so fewer than n discounts
+// may be created.
+func (s *Service) CreateBatch(r *CreateRequest, jobID int64, n int) (int64, error) {
+	template, err := s.FromCreateRequest(r)
+	if err != nil {
+		return 0, err
+	}
+	var pattern *codegen.Pattern
+	if r.Pattern != "" {
+		pattern, err = s.codes.ParsePattern(r.Pattern)
+		if err != nil {
+			return 0, err
+		}
+	}
+	discounts := make([]*discount.Discount, 0, n)
+	seen := make(map[string]bool, n)
+	for attempts := 0; len(discounts) < n && attempts < 10*n; attempts++ {
+		code, err := s.newCode(r.CodePrefix, pattern)
+		if err != nil {
+			return 0, err
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		d := *template
+		d.Code = code
+		d.JobID = &jobID
+		discounts = append(discounts, &d)
+	}
+	return s.discount.InsertBatch(discounts)
+}
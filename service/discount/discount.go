@@ -0,0 +1,458 @@
+package discount
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/config"
+	"discount/internal/schedule"
+	"discount/internal/serr"
+	"discount/service/audit"
+	"discount/storage/discount"
+	"discount/storage/redemption"
+	"errors"
+	"time"
+)
+
+type DTO struct {
+	ID                    int64     `json:"id"`
+	Code                  string    `json:"code"`
+	PercentOff            int64     `json:"percentOff"`
+	DiscountAmount        int64     `json:"discountAmount"`
+	UsageLimit            int64     `json:"usageLimit"`
+	UsedCount             int64     `json:"usedCount"`
+	ExpirationDate        time.Time `json:"expirationDate"`
+	StartDateTime         time.Time `json:"startDateTime"`
+	MaxAmount             int64     `json:"maxAmount"`
+	MinAmount             int64     `json:"minAmount"`
+	Exclusive             bool      `json:"exclusive"`
+	StackableWithGift     bool      `json:"stackableWithGift"`
+	StackableWithDiscount bool      `json:"stackableWithDiscount"`
+	CampaignID            *int64    `json:"campaignId"`
+	CreatedAt             time.Time `json:"createdAt"`
+	UpdatedAt             time.Time `json:"updatedAt"`
+	// Conditions restrict the discount to carts that meet all of them.
+	Conditions discount.Conditions `json:"conditions"`
+	// Schedule restricts the discount to recurring time windows within its validity.
+	Schedule schedule.Schedule `json:"schedule"`
+	// Status is draft, active, paused or archived. It is changed through pause, resume and archive, not by updates.
+	Status string `json:"status"`
+	// Version is raised by every update and status change and is sent as the ETag. An update applies to the
+	// version given by the If-Match header, or else by this field. Updates leave the used count as it is.
+	Version int64 `json:"version"`
+}
+
+type CreateRequest struct {
+	CodePrefix     string `json:"codePrefix"`
+	Code           string `json:"code"`
+	PercentOff     int64  `json:"percentOff"`
+	DiscountAmount int64  `json:"discountAmount"`
+	UsageLimit     int64  `json:"usageLimit"`
+	ExpirationDate string `json:"expirationDate"`
+	StartDateTime  string `json:"startDateTime"`
+	MaxAmount      int64  `json:"maxAmount"`
+	MinAmount      int64  `json:"minAmount"`
+	// Exclusive discounts cannot be combined with any other code. A discount can be combined with gifts but not
+	// with other discounts unless StackableWithGift or StackableWithDiscount says otherwise.
+	Exclusive             bool   `json:"exclusive"`
+	StackableWithGift     *bool  `json:"stackableWithGift"`
+	StackableWithDiscount *bool  `json:"stackableWithDiscount"`
+	CampaignID            *int64 `json:"campaignId"`
+	// Pattern generates the code from a template such as "NOWRUZ-####-??", where # is a digit and ? a letter.
+	// It replaces CodePrefix and cannot be combined with Code.
+	Pattern string `json:"pattern"`
+	// Conditions restrict the discount to carts that meet all of them, for example to some product categories.
+	Conditions discount.Conditions `json:"conditions"`
+	// Schedule restricts the discount to recurring time windows, such as weekends or happy hours, within its
+	// validity.
+	Schedule schedule.Schedule `json:"schedule"`
+	// Status is active, the default, or draft for a discount that cannot be used until it is resumed.
+	Status string `json:"status"`
+}
+
+type ListDTO struct {
+	Items    []*DTO `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+// RedeemRequest describes the order a discount is redeemed on. The conditions of the discount are checked against
+// Cart.
+type RedeemRequest struct {
+	Amount  int64         `json:"amount"`
+	UserID  string        `json:"userId"`
+	OrderID string        `json:"orderId"`
+	Cart    discount.Cart `json:"cart"`
+}
+
+// PriceDTO is the result of applying a discount code to an order amount.
+type PriceDTO struct {
+	Code           string `json:"code"`
+	OrderAmount    int64  `json:"orderAmount"`
+	DiscountAmount int64  `json:"discountAmount"`
+	FinalAmount    int64  `json:"finalAmount"`
+}
+
+// maxCodeAttempts is how many generated codes are tried before giving up on finding one that is not taken.
+const maxCodeAttempts = 10
+
+func (s *Service) Create(actor audit.Actor, r *CreateRequest) (*DTO, error) {
+	discountRecord, err := s.FromCreateRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = discountRecord.Conditions.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = discountRecord.Schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ensureCampaignExists(discountRecord.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := s.codePattern(r, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ensureUniqueDiscountCode(discountRecord, r.CodePrefix, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		err := service.discount.Create(discountRecord)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(discountRecord)
+		return service.audit.Record(actor, audit.ActionCreate, audit.EntityDiscount, result.Code, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) GetByCode(code string) (*DTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.discount.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return s.FromDBModel(d), nil
+}
+
+func (s *Service) List(page, pageSize int) (*ListDTO, error) {
+	discounts, total, err := s.discount.GetAllByPage(pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &ListDTO{Items: make([]*DTO, 0, len(discounts)), Total: total, Page: page, PageSize: pageSize}
+	for _, d := range discounts {
+		result.Items = append(result.Items, s.FromDBModel(d))
+	}
+	return result, nil
+}
+
+// UpdateByCode updates the discount with the given code, provided it is still at r.Version.
+func (s *Service) UpdateByCode(actor audit.Actor, code string, r *DTO) (*DTO, error) {
+	current, err := s.discount.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	discountRecord := s.ToDBModel(r)
+	discountRecord.ID = current.ID
+	discountRecord.Status = current.Status
+
+	err = discountRecord.Conditions.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = discountRecord.Schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ensureCampaignExists(discountRecord.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		err := service.discount.Update(discountRecord)
+		if err != nil {
+			return err
+		}
+		discountRecord.CreatedAt = current.CreatedAt
+		result = s.FromDBModel(discountRecord)
+		return service.audit.Record(actor, audit.ActionUpdate, audit.EntityDiscount, code, s.FromDBModel(current),
+			result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteByCode deletes the discount. It can be restored until the deleted discounts are purged.
+func (s *Service) DeleteByCode(actor audit.Actor, code string) error {
+	current, err := s.discount.GetByCode(code)
+	if err != nil {
+		return err
+	}
+	return s.transaction(func(service *Service) error {
+		err := service.discount.DeleteByCode(code)
+		if errors.Is(err, discount.ErrNoRowToUpdate) {
+			return serr.ValidationErr("code", "invalid discount code", serr.ErrInvalidDiscountCode)
+		}
+		if err != nil {
+			return err
+		}
+		return service.audit.Record(actor, audit.ActionDelete, audit.EntityDiscount, code, s.FromDBModel(current),
+			nil)
+	})
+}
+
+// Restore brings back a deleted discount.
+func (s *Service) Restore(actor audit.Actor, code string) (*DTO, error) {
+	var result *DTO
+	err := s.transaction(func(service *Service) error {
+		d, err := service.discount.Restore(code)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(d)
+		return service.audit.Record(actor, audit.ActionRestore, audit.EntityDiscount, code, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// purgeDeleted removes the discounts that were deleted longer ago than the retention period. Nothing is purged when no
+// retention period is configured. It is called by the scheduler every hour.
+func (s *Service) purgeDeleted() error {
+	retention := config.DeletedRetention()
+	if retention <= 0 {
+		return nil
+	}
+	_, err := s.discount.PurgeDeleted(time.Now().Add(-retention))
+	return err
+}
+
+// Redeem applies the discount code to an order of the given amount and consumes one usage of the code.
+// The usage, the charge to the campaign budget and the redemption record are written in the same transaction.
+func (s *Service) Redeem(code string, r *RedeemRequest) (*PriceDTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.discount.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	price, err := Calculate(d, r.Amount, &r.Cart, now)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		ds, err := s.discount.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		rs, err := s.redemption.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		err = ds.IncreaseUsedCount(code, now)
+		if errors.Is(err, discount.ErrNoRowToUpdate) {
+			return serr.ValidationErr("code", "discount usage limit reached", serr.ErrDiscountUsageLimitReached)
+		}
+		if err != nil {
+			return err
+		}
+		if d.CampaignID != nil {
+			cs, err := s.campaign.WithTX(tx)
+			if err != nil {
+				return err
+			}
+			err = cs.Spend(*d.CampaignID, price.DiscountAmount, now)
+			if err != nil {
+				return err
+			}
+		}
+		return rs.Create(&redemption.Redemption{
+			Code:     code,
+			CodeType: redemption.CodeTypeDiscount,
+			UserID:   r.UserID,
+			OrderID:  r.OrderID,
+			Amount:   price.DiscountAmount,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return price, nil
+}
+
+// Apply calculates the price of an order of the given amount and cart after applying the discount code.
+// It does not consume a usage of the code.
+func (s *Service) Apply(code string, amount int64, cart *discount.Cart) (*PriceDTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.discount.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return Calculate(d, amount, cart, time.Now())
+}
+
+// Calculate applies the discount to an order of the given amount and cart at the given time.
+// The order is refused if the discount is not usable at that time, the amount is below MinAmount or the cart does
+// not meet the conditions of the discount; the conditions are not checked when there is no cart.
+// PercentOff takes precedence over DiscountAmount, the reduction is capped at MaxAmount
+// and it never exceeds the order amount itself.
+func Calculate(d *discount.Discount, amount int64, cart *discount.Cart, now time.Time) (*PriceDTO, error) {
+	if amount <= 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	if err := d.Validate(now); err != nil {
+		return nil, err
+	}
+	if d.MinAmount > 0 && amount < d.MinAmount {
+		return nil, serr.ValidationErr("amount", "order amount is below the discount minimum",
+			serr.ErrDiscountMinAmountNotReached)
+	}
+	if cart != nil && len(d.Conditions.Failed(cart)) > 0 {
+		return nil, serr.ValidationErr("cart", "order does not meet the discount conditions",
+			serr.ErrDiscountConditionsNotMet)
+	}
+
+	off := d.DiscountAmount
+	if d.PercentOff > 0 {
+		off = amount * d.PercentOff / 100
+	}
+	if d.MaxAmount > 0 && off > d.MaxAmount {
+		off = d.MaxAmount
+	}
+	if off > amount {
+		off = amount
+	}
+
+	return &PriceDTO{
+		Code:           d.Code,
+		OrderAmount:    amount,
+		DiscountAmount: off,
+		FinalAmount:    amount - off,
+	}, nil
+}
+
+// ensureCampaignExists checks that the campaign a discount is assigned to exists.
+func (s *Service) ensureCampaignExists(id *int64) error {
+	if id == nil {
+		return nil
+	}
+	_, err := s.campaign.GetByID(*id)
+	if isErrorCode(err, serr.ErrCampaignNotFound) {
+		return serr.ValidationErr("campaignId", "invalid campaign", serr.ErrInvalidCampaign)
+	}
+	return err
+}
+
+// ensureUniqueDiscountCode generates a code for the discount, from the pattern when there is one, unless it already
+// has a code, in which case the code is only checked. Generated codes are random enough that a taken code is rare,
+// and patterns are never filled beyond half of their codes, so a few attempts are made before giving up.
+func (s *Service) ensureUniqueDiscountCode(discountRecord *discount.Discount, codePrefix string, pattern *codegen.Pattern) error {
+	if discountRecord.Code != "" {
+		err := s.codes.Check(discountRecord.Code)
+		if err != nil {
+			return err
+		}
+		taken, err := s.codeTaken(discountRecord.Code)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return serr.ConflictErr("code", "code already exists", serr.ErrCodeAlreadyExists)
+		}
+		return nil
+	}
+	for i := 0; i < maxCodeAttempts; i++ {
+		code, err := s.newCode(codePrefix, pattern)
+		if err != nil {
+			return err
+		}
+		taken, err := s.codeTaken(code)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			discountRecord.Code = code
+			return nil
+		}
+	}
+	return serr.ConflictErr("code", "could not generate a unique code", serr.ErrCodeGenerationFailed)
+}
+
+// codeTaken reports whether a discount already has the code. Deleted discounts count too, as they keep their codes
+// until they are purged.
+func (s *Service) codeTaken(code string) (bool, error) {
+	taken, err := s.discount.ExistingCodes([]string{code})
+	if err != nil {
+		return false, err
+	}
+	return taken[code], nil
+}
+
+// codePattern parses the code pattern of the request, if it has one, and checks that the pattern still allows
+// requested more codes next to the discounts already created from it.
+func (s *Service) codePattern(r *CreateRequest, requested int64) (*codegen.Pattern, error) {
+	if r.Pattern == "" {
+		return nil, nil
+	}
+	if r.Code != "" {
+		return nil, serr.ValidationErr("pattern", "code and pattern cannot both be set", serr.ErrInvalidPattern)
+	}
+	pattern, err := s.codes.ParsePattern(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.discount.CountLike(pattern.Like())
+	if err != nil {
+		return nil, err
+	}
+	return pattern, pattern.Check(used, requested)
+}
+
+// newCode generates a code matching the pattern, or a code with the prefix when there is no pattern.
+func (s *Service) newCode(codePrefix string, pattern *codegen.Pattern) (string, error) {
+	if pattern != nil {
+		return pattern.Generate()
+	}
+	return s.codes.Generate(codePrefix)
+}
+
+func isErrorCode(err error, code serr.ErrorCode) bool {
+	var e *serr.ServiceError
+	return errors.As(err, &e) && e.ErrorCode == code
+}
@@ -0,0 +1,135 @@
+package discount_test
+
+import (
+	"discount/internal/schedule"
+	"discount/internal/serr"
+	discountService "discount/service/discount"
+	"discount/storage/discount"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalculate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		discount  discount.Discount
+		amount    int64
+		cart      *discount.Cart
+		wantOff   int64
+		wantError serr.ErrorCode
+	}{
+		{
+			name:     "percent off",
+			discount: discount.Discount{Code: "TEST", PercentOff: 20},
+			amount:   50000,
+			wantOff:  10000,
+		},
+		{
+			name:     "percent off capped at max amount",
+			discount: discount.Discount{Code: "TEST", PercentOff: 50, MaxAmount: 15000},
+			amount:   100000,
+			wantOff:  15000,
+		},
+		{
+			name:     "fixed amount never exceeds order",
+			discount: discount.Discount{Code: "TEST", DiscountAmount: 30000},
+			amount:   20000,
+			wantOff:  20000,
+		},
+		{
+			name:      "below min amount",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10, MinAmount: 100000},
+			amount:    99999,
+			wantError: serr.ErrDiscountMinAmountNotReached,
+		},
+		{
+			name:      "not started",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10, StartDateTime: now.Add(time.Hour)},
+			amount:    1000,
+			wantError: serr.ErrDiscountNotStarted,
+		},
+		{
+			name:      "expired",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10, ExpirationDate: now.Add(-time.Hour)},
+			amount:    1000,
+			wantError: serr.ErrDiscountExpired,
+		},
+		{
+			name:      "usage limit reached",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10, UsageLimit: 5, UsedCount: 5},
+			amount:    1000,
+			wantError: serr.ErrDiscountUsageLimitReached,
+		},
+		{
+			name: "conditions met",
+			discount: discount.Discount{Code: "TEST", PercentOff: 10, Conditions: discount.Conditions{
+				{Type: discount.ConditionCategory, Operator: discount.OperatorIn, Values: []string{"books"}},
+				{Type: discount.ConditionChannel, Operator: discount.OperatorNotIn, Values: []string{"pos"}},
+			}},
+			amount:  1000,
+			cart:    &discount.Cart{Channel: "web", Items: []discount.CartItem{{SKU: "B1", Category: "books"}}},
+			wantOff: 100,
+		},
+		{
+			name: "conditions not met",
+			discount: discount.Discount{Code: "TEST", PercentOff: 10, Conditions: discount.Conditions{
+				{Type: discount.ConditionSegment, Operator: discount.OperatorIn, Values: []string{"vip"}},
+			}},
+			amount:    1000,
+			cart:      &discount.Cart{Segments: []string{"new"}},
+			wantError: serr.ErrDiscountConditionsNotMet,
+		},
+		{
+			name: "conditions not checked without cart",
+			discount: discount.Discount{Code: "TEST", PercentOff: 10, Conditions: discount.Conditions{
+				{Type: discount.ConditionSKU, Operator: discount.OperatorIn, Values: []string{"B1"}},
+			}},
+			amount:  1000,
+			wantOff: 100,
+		},
+		{
+			name: "outside schedule",
+			discount: discount.Discount{Code: "TEST", PercentOff: 10, Schedule: schedule.Schedule{
+				{Days: []string{now.UTC().Add(24 * time.Hour).Weekday().String()}},
+			}},
+			amount:    1000,
+			wantError: serr.ErrDiscountOutOfSchedule,
+		},
+		{
+			name:      "paused",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10, Status: discount.StatusPaused},
+			amount:    1000,
+			wantError: serr.ErrDiscountPaused,
+		},
+		{
+			name:      "invalid amount",
+			discount:  discount.Discount{Code: "TEST", PercentOff: 10},
+			amount:    0,
+			wantError: serr.ErrInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discountService.Calculate(&tt.discount, tt.amount, tt.cart, now)
+			if tt.wantError != "" {
+				var e *serr.ServiceError
+				if !errors.As(err, &e) || e.ErrorCode != tt.wantError {
+					t.Fatalf("expected %v, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.DiscountAmount != tt.wantOff {
+				t.Fatalf("expected %v, got %v", tt.wantOff, got.DiscountAmount)
+			}
+			if got.FinalAmount != tt.amount-tt.wantOff {
+				t.Fatalf("expected %v, got %v", tt.amount-tt.wantOff, got.FinalAmount)
+			}
+		})
+	}
+}
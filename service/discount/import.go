@@ -0,0 +1,44 @@
+package discount
+
+import (
+	"discount/internal/serr"
+	"discount/storage/discount"
+)
+
+// ImportRequest is a discount moved over from another system. Unlike a create request it must carry its code, and
+// it keeps how many times the discount has already been used.
+type ImportRequest struct {
+	CreateRequest
+	UsedCount int64 `json:"usedCount"`
+}
+
+// FromImportRequest validates an imported discount and returns the discount to insert. The code is not checked
+// against existing discounts, and the campaign is not checked either.
+func (s *Service) FromImportRequest(r *ImportRequest) (*discount.Discount, error) {
+	if r.Code == "" {
+		return nil, serr.ValidationErr("code", "code is required", serr.ErrInvalidImportRecord)
+	}
+	err := s.codes.Check(r.Code)
+	if err != nil {
+		return nil, err
+	}
+	if r.PercentOff < 0 || r.PercentOff > 100 || r.DiscountAmount < 0 || (r.PercentOff == 0 && r.DiscountAmount == 0) {
+		return nil, serr.ValidationErr("percentOff", "invalid amount", serr.ErrInvalidAmount)
+	}
+	if r.UsedCount < 0 || (r.UsageLimit > 0 && r.UsedCount > r.UsageLimit) {
+		return nil, serr.ValidationErr("usedCount", "invalid used count", serr.ErrInvalidImportRecord)
+	}
+	if err = r.Conditions.Validate(); err != nil {
+		return nil, err
+	}
+	if err = r.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	d, err := s.FromCreateRequest(&r.CreateRequest)
+	if err != nil {
+		return nil, err
+	}
+	d.UsedCount = r.UsedCount
+	return d, nil
+}
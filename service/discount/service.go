@@ -0,0 +1,177 @@
+package discount
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/date"
+	"discount/internal/serr"
+	"discount/service/audit"
+	"discount/storage/campaign"
+	"discount/storage/discount"
+	"discount/storage/redemption"
+	"github.com/jasonlvhit/gocron"
+	"github.com/rs/zerolog/log"
+)
+
+type Service struct {
+	discount   discount.Storage
+	redemption redemption.Storage
+	campaign   campaign.Storage
+	codes      *codegen.Generator
+	audit      *audit.Service
+}
+
+func New(
+	discount discount.Storage,
+	redemption redemption.Storage,
+	campaign campaign.Storage,
+	codes *codegen.Generator,
+	audit *audit.Service,
+) *Service {
+	s := &Service{
+		discount:   discount,
+		redemption: redemption,
+		campaign:   campaign,
+		codes:      codes,
+		audit:      audit,
+	}
+	err := gocron.Every(1).Hour().Do(func() {
+		if err := s.purgeDeleted(); err != nil {
+			log.Error().Err(err).Msg("failed to purge deleted discounts")
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start gocron for purging deleted discounts")
+	}
+	return s
+}
+
+func (s *Service) withTX(tx *sql.Tx) (*Service, error) {
+	service := *s
+	d, err := s.discount.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.discount = d
+	a, err := s.audit.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.audit = a
+	return &service, nil
+}
+
+// transaction runs fn on a service bound to a new database transaction, so a change and its audit entry are
+// committed together.
+func (s *Service) transaction(fn func(service *Service) error) error {
+	return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		service, err := s.withTX(tx)
+		if err != nil {
+			return err
+		}
+		return fn(service)
+	})
+}
+
+func (s *Service) ToDBModel(d *DTO) *discount.Discount {
+	return &discount.Discount{
+		ID:                    d.ID,
+		Code:                  d.Code,
+		PercentOff:            d.PercentOff,
+		DiscountAmount:        d.DiscountAmount,
+		UsageLimit:            d.UsageLimit,
+		UsedCount:             d.UsedCount,
+		ExpirationDate:        d.ExpirationDate,
+		StartDateTime:         d.StartDateTime,
+		MaxAmount:             d.MaxAmount,
+		MinAmount:             d.MinAmount,
+		Exclusive:             d.Exclusive,
+		StackableWithGift:     d.StackableWithGift,
+		StackableWithDiscount: d.StackableWithDiscount,
+		CampaignID:            d.CampaignID,
+		Conditions:            d.Conditions,
+		Schedule:              d.Schedule,
+		Status:                d.Status,
+		Version:               d.Version,
+	}
+}
+
+func (s *Service) FromDBModel(d *discount.Discount) *DTO {
+	return &DTO{
+		ID:                    d.ID,
+		Code:                  d.Code,
+		PercentOff:            d.PercentOff,
+		DiscountAmount:        d.DiscountAmount,
+		UsageLimit:            d.UsageLimit,
+		UsedCount:             d.UsedCount,
+		ExpirationDate:        d.ExpirationDate,
+		StartDateTime:         d.StartDateTime,
+		MaxAmount:             d.MaxAmount,
+		MinAmount:             d.MinAmount,
+		Exclusive:             d.Exclusive,
+		StackableWithGift:     d.StackableWithGift,
+		StackableWithDiscount: d.StackableWithDiscount,
+		CampaignID:            d.CampaignID,
+		Conditions:            d.Conditions,
+		Schedule:              d.Schedule,
+		Status:                d.Status,
+		CreatedAt:             d.CreatedAt,
+		UpdatedAt:             d.UpdatedAt,
+		Version:               d.Version,
+	}
+}
+
+// FromCreateRequest returns the discount described by the request. Dates are RFC3339 timestamps or dates in the
+// business timezone; a malformed date is a validation error.
+func (s *Service) FromCreateRequest(r *CreateRequest) (*discount.Discount, error) {
+	stDate, err := date.Parse("startDateTime", r.StartDateTime)
+	if err != nil {
+		return nil, err
+	}
+	exDate, err := date.Parse("expirationDate", r.ExpirationDate)
+	if err != nil {
+		return nil, err
+	}
+	status, err := initialStatus(r.Status)
+	if err != nil {
+		return nil, err
+	}
+	return &discount.Discount{
+		Code:                  r.Code,
+		PercentOff:            r.PercentOff,
+		DiscountAmount:        r.DiscountAmount,
+		UsageLimit:            r.UsageLimit,
+		ExpirationDate:        exDate,
+		StartDateTime:         stDate,
+		MaxAmount:             r.MaxAmount,
+		MinAmount:             r.MinAmount,
+		Exclusive:             r.Exclusive,
+		StackableWithGift:     boolOr(r.StackableWithGift, true),
+		StackableWithDiscount: boolOr(r.StackableWithDiscount, false),
+		CampaignID:            r.CampaignID,
+		Conditions:            r.Conditions,
+		Schedule:              r.Schedule,
+		Status:                status,
+	}, nil
+}
+
+// initialStatus is the status a discount is created with: active, unless the request asks for a draft.
+func initialStatus(status string) (string, error) {
+	switch status {
+	case "":
+		return discount.StatusActive, nil
+	case discount.StatusDraft, discount.StatusActive:
+		return status, nil
+	}
+	return "", serr.ValidationErr("status", "invalid status", serr.ErrInvalidStatus)
+}
+
+// boolOr returns the value p points to, or def when p is nil.
+func boolOr(p *bool, def bool) bool {
+	if p == nil {
+		return def
+	}
+	return *p
+}
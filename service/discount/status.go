@@ -0,0 +1,45 @@
+package discount
+
+import (
+	"discount/service/audit"
+	"discount/storage/discount"
+)
+
+// Pause stops the discount from being used until it is resumed.
+func (s *Service) Pause(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionPause, code, discount.StatusPaused)
+}
+
+// Resume makes a paused or draft discount usable again.
+func (s *Service) Resume(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionResume, code, discount.StatusActive)
+}
+
+// Archive retires the discount for good. Unlike deleting it, its redemptions and history are kept.
+func (s *Service) Archive(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionArchive, code, discount.StatusArchived)
+}
+
+func (s *Service) setStatus(actor audit.Actor, action, code, status string) (*DTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.discount.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		d, err := service.discount.SetStatus(code, status)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(d)
+		return service.audit.Record(actor, action, audit.EntityDiscount, code, s.FromDBModel(current), result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"discount/internal/date"
+	"discount/internal/serr"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	redemptionStorage "discount/storage/redemption"
+	"encoding/csv"
+	"github.com/rs/zerolog/log"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Request filters an export. From and To are dates, both inclusive, bounding when the codes were created or the
+// redemptions were made. Prefix matches the start of the code. Status is scheduled, active, expired, exhausted,
+// draft, paused or archived for gifts and discounts, and active or reversed for redemptions, whose code type can be
+// narrowed down as well.
+type Request struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Prefix   string `form:"prefix"`
+	Status   string `form:"status"`
+	CodeType string `form:"type"`
+}
+
+// WriteGifts writes the gifts matching the request to w as CSV, streaming them from the database. Usages still
+// held in Redis are synced first so the used counts are up to date.
+func (s *Service) WriteGifts(r *Request, w io.Writer) error {
+	from, to, err := dateRange(r)
+	if err != nil {
+		return err
+	}
+	err = checkStatus(r.Status, giftStorage.StatusScheduled, giftStorage.StatusActive, giftStorage.StatusExpired,
+		giftStorage.StatusExhausted, giftStorage.StatusDraft, giftStorage.StatusPaused, giftStorage.StatusArchived)
+	if err != nil {
+		return err
+	}
+	if err = s.gift.SyncRedisWithDB(); err != nil {
+		log.Error().Err(err).Msg("failed to sync gifts before export")
+	}
+
+	now := time.Now()
+	cw := csv.NewWriter(w)
+	err = cw.Write([]string{"id", "code", "gift_amount", "usage_limit", "used_count", "balance_mode",
+		"remaining_balance", "start_date_time", "expiration_date", "campaign_id", "status", "created_at"})
+	if err != nil {
+		return err
+	}
+	f := &giftStorage.Filter{From: from, To: to, Prefix: r.Prefix, Status: r.Status}
+	err = s.gift.ForEach(f, now, func(g *giftStorage.Gift) error {
+		return cw.Write([]string{itoa(g.ID), g.Code, itoa(g.GiftAmount), itoa(g.UsageLimit), itoa(g.UsedCount),
+			strconv.FormatBool(g.BalanceMode), itoa(g.RemainingBalance), formatTime(g.StartDateTime),
+			formatTime(g.ExpirationDate), formatID(g.CampaignID), g.StatusAt(now), formatTime(g.CreatedAt)})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDiscounts writes the discounts matching the request to w as CSV, streaming them from the database.
+func (s *Service) WriteDiscounts(r *Request, w io.Writer) error {
+	from, to, err := dateRange(r)
+	if err != nil {
+		return err
+	}
+	err = checkStatus(r.Status, discountStorage.StatusScheduled, discountStorage.StatusActive,
+		discountStorage.StatusExpired, discountStorage.StatusExhausted, discountStorage.StatusDraft,
+		discountStorage.StatusPaused, discountStorage.StatusArchived)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cw := csv.NewWriter(w)
+	err = cw.Write([]string{"id", "code", "percent_off", "discount_amount", "max_amount", "min_amount",
+		"usage_limit", "used_count", "start_date_time", "expiration_date", "campaign_id", "status", "created_at"})
+	if err != nil {
+		return err
+	}
+	f := &discountStorage.Filter{From: from, To: to, Prefix: r.Prefix, Status: r.Status}
+	err = s.discount.ForEach(f, now, func(d *discountStorage.Discount) error {
+		return cw.Write([]string{itoa(d.ID), d.Code, itoa(d.PercentOff), itoa(d.DiscountAmount), itoa(d.MaxAmount),
+			itoa(d.MinAmount), itoa(d.UsageLimit), itoa(d.UsedCount), formatTime(d.StartDateTime),
+			formatTime(d.ExpirationDate), formatID(d.CampaignID), d.StatusAt(now), formatTime(d.CreatedAt)})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteRedemptions writes the redemptions matching the request to w as CSV, streaming them from the database.
+func (s *Service) WriteRedemptions(r *Request, w io.Writer) error {
+	from, to, err := dateRange(r)
+	if err != nil {
+		return err
+	}
+	err = checkStatus(r.Status, redemptionStorage.StatusActive, redemptionStorage.StatusReversed)
+	if err != nil {
+		return err
+	}
+	switch r.CodeType {
+	case "", redemptionStorage.CodeTypeGift, redemptionStorage.CodeTypeDiscount:
+	default:
+		return serr.ValidationErr("type", "invalid code type", serr.ErrInvalidCodeType)
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write([]string{"id", "code", "code_type", "user_id", "order_id", "amount", "created_at", "reversed_at"})
+	if err != nil {
+		return err
+	}
+	f := &redemptionStorage.Filter{From: from, To: to, Prefix: r.Prefix, Status: r.Status, CodeType: r.CodeType}
+	err = s.redemption.ForEach(f, func(rd *redemptionStorage.Redemption) error {
+		reversedAt := ""
+		if rd.ReversedAt != nil {
+			reversedAt = formatTime(*rd.ReversedAt)
+		}
+		return cw.Write([]string{itoa(rd.ID), rd.Code, rd.CodeType, rd.UserID, rd.OrderID, itoa(rd.Amount),
+			formatTime(rd.CreatedAt), reversedAt})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// dateRange parses the dates of the request, which are days in the business timezone, into a time range,
+// To exclusive.
+func dateRange(r *Request) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if r.From != "" {
+		from, err = date.ParseDay(r.From)
+		if err != nil {
+			return from, to, serr.ValidationErr("from", "invalid date", serr.ErrInvalidFilter)
+		}
+	}
+	if r.To != "" {
+		to, err = date.ParseDay(r.To)
+		if err != nil {
+			return from, to, serr.ValidationErr("to", "invalid date", serr.ErrInvalidFilter)
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return from, to, serr.ValidationErr("to", "invalid date range", serr.ErrInvalidFilter)
+	}
+	return from, to, nil
+}
+
+// checkStatus checks that the status filter is empty or one of the allowed statuses, before anything is written.
+func checkStatus(status string, allowed ...string) error {
+	if status == "" || slices.Contains(allowed, status) {
+		return nil
+	}
+	return serr.ValidationErr("status", "invalid status", serr.ErrInvalidFilter)
+}
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+func formatID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return itoa(*id)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
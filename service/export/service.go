@@ -0,0 +1,25 @@
+package export
+
+import (
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	redemptionStorage "discount/storage/redemption"
+)
+
+type Service struct {
+	gift       giftStorage.Storage
+	discount   discountStorage.Storage
+	redemption redemptionStorage.Storage
+}
+
+func New(
+	gift giftStorage.Storage,
+	discount discountStorage.Storage,
+	redemption redemptionStorage.Storage,
+) *Service {
+	return &Service{
+		gift:       gift,
+		discount:   discount,
+		redemption: redemption,
+	}
+}
@@ -0,0 +1,91 @@
+package gift
+
+import (
+	"discount/internal/serr"
+	"discount/storage/redemption"
+	"github.com/rs/zerolog/log"
+)
+
+// DebitRequest describes an amount spent from a balance mode gift, and who spent it for which order.
+type DebitRequest struct {
+	UserID  string `json:"userId"`
+	OrderID string `json:"orderId"`
+	Amount  int64  `json:"amount"`
+}
+
+// BalanceDTO is the balance of a balance mode gift. Debited is only set in the response of a debit.
+type BalanceDTO struct {
+	Code             string `json:"code"`
+	GiftAmount       int64  `json:"giftAmount"`
+	RemainingBalance int64  `json:"remainingBalance"`
+	Debited          int64  `json:"debited,omitempty"`
+}
+
+// Debit spends r.Amount from the balance of the gift and records the redemption. It fails without debiting
+// anything when the remaining balance is lower than the amount. Like UseGift, the amount is charged to the
+// campaign budget first.
+func (s *Service) Debit(code string, r *DebitRequest) (*BalanceDTO, error) {
+	if r.Amount <= 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.gift.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	err = s.spend(current, r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.gift.DebitBalanceRedis(code, r.Amount)
+	if err != nil {
+		s.refund(current, r.Amount)
+		return nil, err
+	}
+
+	err = s.redemption.Create(&redemption.Redemption{
+		Code:     g.Code,
+		CodeType: redemption.CodeTypeGift,
+		UserID:   r.UserID,
+		OrderID:  r.OrderID,
+		Amount:   r.Amount,
+	})
+	if err != nil {
+		// The debit is not in the ledger, so it is credited back rather than left unrecorded.
+		if _, e := s.gift.DecreaseUsedCountRedis(code, r.Amount); e != nil {
+			log.Error().Err(e).Str("code", code).Msg("failed to credit back gift debit")
+		}
+		s.refund(current, r.Amount)
+		return nil, serr.DBError("Debit", "redemption", err)
+	}
+
+	return &BalanceDTO{
+		Code:             g.Code,
+		GiftAmount:       g.GiftAmount,
+		RemainingBalance: g.RemainingBalance,
+		Debited:          r.Amount,
+	}, nil
+}
+
+// Balance returns the remaining balance of a balance mode gift, including debits not yet synced to the database.
+func (s *Service) Balance(code string) (*BalanceDTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.gift.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if !g.BalanceMode {
+		return nil, serr.ValidationErr("code", "gift is not a balance gift", serr.ErrGiftNotBalanceMode)
+	}
+	return &BalanceDTO{
+		Code:             g.Code,
+		GiftAmount:       g.GiftAmount,
+		RemainingBalance: g.RemainingBalance,
+	}, nil
+}
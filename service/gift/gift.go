@@ -1,21 +1,44 @@
 package gift
 
 import (
+	"discount/internal/codegen"
+	"discount/internal/config"
+	"discount/internal/date"
+	"discount/internal/schedule"
+	"discount/internal/serr"
+	"discount/service/audit"
 	"discount/storage/gift"
-	"fmt"
+	"discount/storage/redemption"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"strings"
 	"time"
 )
 
 type DTO struct {
-	ID             int64     `json:"id"`
-	Code           string    `json:"code"`
-	GiftAmount     int64     `json:"giftAmount"`
-	UsageLimit     int64     `json:"usageLimit"`
-	UsedCount      int64     `json:"usedCount"`
-	ExpirationDate time.Time `json:"expirationDate"`
-	StartDateTime  time.Time `json:"startDateTime"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID                    int64     `json:"id"`
+	Code                  string    `json:"code"`
+	GiftAmount            int64     `json:"giftAmount"`
+	UsageLimit            int64     `json:"usageLimit"`
+	UsedCount             int64     `json:"usedCount"`
+	ExpirationDate        time.Time `json:"expirationDate"`
+	StartDateTime         time.Time `json:"startDateTime"`
+	BalanceMode           bool      `json:"balanceMode"`
+	RemainingBalance      int64     `json:"remainingBalance"`
+	Exclusive             bool      `json:"exclusive"`
+	StackableWithGift     bool      `json:"stackableWithGift"`
+	StackableWithDiscount bool      `json:"stackableWithDiscount"`
+	CampaignID            *int64    `json:"campaignId"`
+	CreatedAt             time.Time `json:"createdAt"`
+	UpdatedAt             time.Time `json:"updatedAt"`
+	// Schedule restricts the gift to recurring time windows within its validity.
+	Schedule schedule.Schedule `json:"schedule"`
+	// Status is draft, active, paused or archived. It is changed through pause, resume and archive, not by updates.
+	Status string `json:"status"`
+	// Version is raised by every update and status change and is sent as the ETag. An update applies to the
+	// version given by the If-Match header, or else by this field. Updates leave the used count and remaining
+	// balance as they are.
+	Version int64 `json:"version"`
 }
 
 type CreateRequest struct {
@@ -25,6 +48,59 @@ type CreateRequest struct {
 	UsageLimit     int64  `json:"usageLimit"`
 	ExpirationDate string `json:"expirationDate"`
 	StartDateTime  string `json:"startDateTime"`
+	// BalanceMode creates a stored-value gift whose GiftAmount is spent over several debits.
+	BalanceMode bool `json:"balanceMode"`
+	// Exclusive gifts cannot be combined with any other code. A gift can be combined with other gifts and
+	// with discounts unless StackableWithGift or StackableWithDiscount is set to false.
+	Exclusive             bool   `json:"exclusive"`
+	StackableWithGift     *bool  `json:"stackableWithGift"`
+	StackableWithDiscount *bool  `json:"stackableWithDiscount"`
+	CampaignID            *int64 `json:"campaignId"`
+	// Pattern generates the code from a template such as "NOWRUZ-####-??", where # is a digit and ? a letter.
+	// It replaces CodePrefix and cannot be combined with Code.
+	Pattern string `json:"pattern"`
+	// Schedule restricts the gift to recurring time windows, such as weekends or happy hours, within its validity.
+	Schedule schedule.Schedule `json:"schedule"`
+	// Status is active, the default, or draft for a gift that cannot be used until it is resumed.
+	Status string `json:"status"`
+}
+
+type ListDTO struct {
+	Items    []*DTO `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+// ListRequest filters and sorts the gifts listed by List. MinAmount and MaxAmount bound the gift amount and
+// ExpiresFrom and ExpiresTo, dates, the expiration date, all of them inclusive. Status is scheduled, active,
+// expired, exhausted, draft, paused or archived. Sort is a comma separated list of fields, each in descending order
+// when prefixed with a minus, such as "-giftAmount,code".
+type ListRequest struct {
+	Prefix      string `form:"prefix"`
+	MinAmount   int64  `form:"minAmount"`
+	MaxAmount   int64  `form:"maxAmount"`
+	ExpiresFrom string `form:"expiresFrom"`
+	ExpiresTo   string `form:"expiresTo"`
+	Status      string `form:"status"`
+	CampaignID  int64  `form:"campaignId"`
+	Sort        string `form:"sort"`
+}
+
+// PriceDTO is the result of applying a gift code to an order amount.
+type PriceDTO struct {
+	Code           string `json:"code"`
+	OrderAmount    int64  `json:"orderAmount"`
+	DiscountAmount int64  `json:"discountAmount"`
+	FinalAmount    int64  `json:"finalAmount"`
+}
+
+// UseRequest describes who uses a gift and for which order. Amount is the order amount; when it is set,
+// the amount recorded for the redemption is capped at it.
+type UseRequest struct {
+	UserID  string `json:"userId"`
+	OrderID string `json:"orderId"`
+	Amount  int64  `json:"amount"`
 }
 
 type UseGiftRequest struct {
@@ -33,6 +109,9 @@ type UseGiftRequest struct {
 	ErrorChan    chan error
 }
 
+// maxCodeAttempts is how many generated codes are tried before giving up on finding one that is not taken.
+const maxCodeAttempts = 10
+
 var useGiftQueue = make(chan UseGiftRequest, 100) // adjust size as needed
 
 func (s *Service) StartProcessingGifts() {
@@ -46,25 +125,52 @@ func (s *Service) StartProcessingGifts() {
 	}
 }
 
-func (s *Service) Create(r *CreateRequest) (*DTO, error) {
-	var giftRecord *gift.Gift
+func (s *Service) Create(actor audit.Actor, r *CreateRequest) (*DTO, error) {
+	giftRecord, err := s.FromCreateRequest(r)
+	if err != nil {
+		return nil, err
+	}
 
-	giftRecord = s.FromCreateRequest(r)
+	err = giftRecord.Schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.ensureUniqueGiftCode(giftRecord, r.CodePrefix)
+	err = s.ensureCampaignExists(giftRecord.CampaignID)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.gift.Create(giftRecord)
+	pattern, err := s.codePattern(r, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.FromDBModel(giftRecord), nil
+	err = s.ensureUniqueGiftCode(giftRecord, r.CodePrefix, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *DTO
+	err = s.transaction(func(service *Service) error {
+		err := service.gift.Create(giftRecord)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(giftRecord)
+		return service.audit.Record(actor, audit.ActionCreate, audit.EntityGift, result.Code, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (s *Service) GetByCode(code string) (*DTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
 	g, err := s.gift.GetByCode(code)
 	if err != nil {
 		return nil, err
@@ -72,28 +178,200 @@ func (s *Service) GetByCode(code string) (*DTO, error) {
 	return s.FromDBModel(g), nil
 }
 
-func (s *Service) UpdateByCode(r *DTO) (*DTO, error) {
+// List returns the gifts matching the request page by page. Used counts are read from the database, so uses that
+// have not been synced from Redis yet are left out.
+func (s *Service) List(r *ListRequest, page, pageSize int) (*ListDTO, error) {
+	f := &gift.Filter{
+		Prefix:     r.Prefix,
+		Status:     r.Status,
+		MinAmount:  r.MinAmount,
+		MaxAmount:  r.MaxAmount,
+		CampaignID: r.CampaignID,
+	}
+	var err error
+	if r.ExpiresFrom != "" {
+		f.ExpiresFrom, err = date.ParseDay(r.ExpiresFrom)
+		if err != nil {
+			return nil, serr.ValidationErr("expiresFrom", "invalid date", serr.ErrInvalidFilter)
+		}
+	}
+	if r.ExpiresTo != "" {
+		f.ExpiresTo, err = date.ParseDay(r.ExpiresTo)
+		if err != nil {
+			return nil, serr.ValidationErr("expiresTo", "invalid date", serr.ErrInvalidFilter)
+		}
+		f.ExpiresTo = f.ExpiresTo.AddDate(0, 0, 1)
+	}
+	gifts, total, err := s.gift.GetAllByPage(f, sortOrders(r.Sort), time.Now(), pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &ListDTO{Items: make([]*DTO, 0, len(gifts)), Total: total, Page: page, PageSize: pageSize}
+	for _, g := range gifts {
+		result.Items = append(result.Items, s.FromDBModel(g))
+	}
+	return result, nil
+}
+
+// sortOrders parses a comma separated list of sort fields, each in descending order when prefixed with a minus.
+func sortOrders(sort string) []gift.Order {
+	var orders []gift.Order
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		orders = append(orders, gift.Order{Field: strings.TrimPrefix(field, "-"), Desc: strings.HasPrefix(field, "-")})
+	}
+	return orders
+}
+
+// UpdateByCode updates the gift with the code of r, provided it is still at r.Version.
+func (s *Service) UpdateByCode(actor audit.Actor, r *DTO) (*DTO, error) {
+	current, err := s.gift.GetByCode(r.Code)
+	if err != nil {
+		return nil, err
+	}
 	giftRecord := s.ToDBModel(r)
+	giftRecord.ID = current.ID
+	giftRecord.Status = current.Status
+
+	err = giftRecord.Schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.gift.UpdateDirectDb(giftRecord)
+	err = s.ensureCampaignExists(giftRecord.CampaignID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.FromDBModel(giftRecord), nil
+	giftRecord.CreatedAt = current.CreatedAt
+
+	var result *DTO
+	err = s.change(giftRecord.Code, func(service *Service) error {
+		err := service.gift.Update(giftRecord)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(giftRecord)
+		return service.audit.Record(actor, audit.ActionUpdate, audit.EntityGift, result.Code, s.FromDBModel(current),
+			result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (s *Service) UseGift(code string) (*DTO, error) {
+// UseGift consumes one usage of the gift. When the gift belongs to a campaign, the used amount is charged to the
+// campaign budget first and given back if the gift cannot be used.
+func (s *Service) UseGift(code string, r *UseRequest) (*DTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	g, err := s.gift.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	amount := usedAmount(g.GiftAmount, r.Amount)
+	err = s.spend(g, amount)
+	if err != nil {
+		return nil, err
+	}
+
 	responseChan := make(chan *DTO)
 	errorChan := make(chan error)
 	useGiftQueue <- UseGiftRequest{Code: code, ResponseChan: responseChan, ErrorChan: errorChan}
 	go s.StartProcessingGifts()
 	select {
 	case response := <-responseChan:
+		err = s.recordRedemption(response, r)
+		if err != nil {
+			s.refund(g, amount)
+			return nil, err
+		}
 		return response, nil
 	case err := <-errorChan:
+		s.refund(g, amount)
+		return nil, err
+	}
+}
+
+// recordRedemption writes the use of a gift to the redemption ledger. The gift has already been consumed at this
+// point, so when the ledger cannot be written the usage is given back and the error returned; a use never goes
+// missing from the ledger.
+func (s *Service) recordRedemption(g *DTO, r *UseRequest) error {
+	err := s.redemption.Create(&redemption.Redemption{
+		Code:     g.Code,
+		CodeType: redemption.CodeTypeGift,
+		UserID:   r.UserID,
+		OrderID:  r.OrderID,
+		Amount:   usedAmount(g.GiftAmount, r.Amount),
+	})
+	if err == nil {
+		return nil
+	}
+	if _, e := s.gift.DecreaseUsedCountRedis(g.Code, 0); e != nil {
+		log.Error().Err(e).Str("code", g.Code).Msg("failed to give back gift usage")
+	}
+	return serr.DBError("recordRedemption", "redemption", err)
+}
+
+// usedAmount is the part of the gift amount used on an order. When the order amount is not known, the whole
+// gift amount is used.
+func usedAmount(giftAmount, orderAmount int64) int64 {
+	if orderAmount > 0 && orderAmount < giftAmount {
+		return orderAmount
+	}
+	return giftAmount
+}
+
+// spend charges amount to the budget of the campaign the gift belongs to, if any.
+func (s *Service) spend(g *gift.Gift, amount int64) error {
+	if g.CampaignID == nil {
+		return nil
+	}
+	return s.campaign.Spend(*g.CampaignID, amount, time.Now())
+}
+
+// refund gives back an amount charged by spend when the gift could not be used after all.
+func (s *Service) refund(g *gift.Gift, amount int64) {
+	if g.CampaignID == nil {
+		return
+	}
+	err := s.campaign.Refund(*g.CampaignID, amount)
+	if err != nil {
+		log.Error().Err(err).Str("code", g.Code).Msg("failed to refund campaign budget")
+	}
+}
+
+// Calculate applies the gift to an order of the given amount at the given time.
+// The gift amount, or the remaining balance of a balance mode gift, is taken off the order, but never more than
+// the order amount itself.
+func Calculate(g *gift.Gift, amount int64, now time.Time) (*PriceDTO, error) {
+	if amount <= 0 {
+		return nil, serr.ValidationErr("amount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	if err := g.Validate(now); err != nil {
 		return nil, err
 	}
+
+	off := g.GiftAmount
+	if g.BalanceMode {
+		off = g.RemainingBalance
+	}
+	if off > amount {
+		off = amount
+	}
+
+	return &PriceDTO{
+		Code:           g.Code,
+		OrderAmount:    amount,
+		DiscountAmount: off,
+		FinalAmount:    amount - off,
+	}, nil
 }
 
 // SyncGifts syncs updates gifts in redis to the database.
@@ -106,27 +384,138 @@ func (s *Service) syncGift() error {
 	return nil
 }
 
-// ensureUniqueGiftCode ensures that the gift code is unique by generating a unique code and checking against existing codes.
-// If the gift already has a code, the function returns nil without generating a new code.
-// If the generated code already exists, it generates a new code and continues checking until a unique code is found.
-func (s *Service) ensureUniqueGiftCode(giftRecord *gift.Gift, codePrefix string) error {
+// DeleteByCode deletes the gift. It can be restored until the deleted gifts are purged.
+func (s *Service) DeleteByCode(actor audit.Actor, code string) error {
+	current, err := s.gift.GetByCode(code)
+	if err != nil {
+		return err
+	}
+	return s.change(code, func(service *Service) error {
+		err := service.gift.DeleteByCode(code)
+		if errors.Is(err, gift.ErrNoRowToUpdate) {
+			return serr.ValidationErr("code", "invalid gift code", serr.ErrInvalidGiftCode)
+		}
+		if err != nil {
+			return err
+		}
+		return service.audit.Record(actor, audit.ActionDelete, audit.EntityGift, code, s.FromDBModel(current), nil)
+	})
+}
+
+// Restore brings back a deleted gift.
+func (s *Service) Restore(actor audit.Actor, code string) (*DTO, error) {
+	var result *DTO
+	err := s.transaction(func(service *Service) error {
+		g, err := service.gift.Restore(code)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(g)
+		return service.audit.Record(actor, audit.ActionRestore, audit.EntityGift, code, nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// purgeDeleted removes the gifts that were deleted longer ago than the retention period. Nothing is purged when no
+// retention period is configured. It is called by the scheduler every hour.
+func (s *Service) purgeDeleted() error {
+	retention := config.DeletedRetention()
+	if retention <= 0 {
+		return nil
+	}
+	_, err := s.gift.PurgeDeleted(time.Now().Add(-retention))
+	return err
+}
+
+// ensureCampaignExists checks that the campaign a gift is assigned to exists.
+func (s *Service) ensureCampaignExists(id *int64) error {
+	if id == nil {
+		return nil
+	}
+	_, err := s.campaign.GetByID(*id)
+	if isErrorCode(err, serr.ErrCampaignNotFound) {
+		return serr.ValidationErr("campaignId", "invalid campaign", serr.ErrInvalidCampaign)
+	}
+	return err
+}
+
+// ensureUniqueGiftCode generates a code for the gift, from the pattern when there is one, unless it already has a
+// code, in which case the code is only checked. Generated codes are random enough that a taken code is rare, and
+// patterns are never filled beyond half of their codes, so a few attempts are made before giving up.
+func (s *Service) ensureUniqueGiftCode(giftRecord *gift.Gift, codePrefix string, pattern *codegen.Pattern) error {
 	if giftRecord.Code != "" {
+		err := s.codes.Check(giftRecord.Code)
+		if err != nil {
+			return err
+		}
+		taken, err := s.codeTaken(giftRecord.Code)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return serr.ConflictErr("code", "code already exists", serr.ErrCodeAlreadyExists)
+		}
 		return nil
 	}
-	giftRecord.Code = s.generateCode(codePrefix)
-	for {
-		g, _ := s.gift.GetByCode(giftRecord.Code)
-		if g == nil {
-			break
+	for i := 0; i < maxCodeAttempts; i++ {
+		code, err := s.newCode(codePrefix, pattern)
+		if err != nil {
+			return err
+		}
+		taken, err := s.codeTaken(code)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			giftRecord.Code = code
+			return nil
 		}
-		giftRecord.Code = s.generateCode(codePrefix)
 	}
-	return nil
+	return serr.ConflictErr("code", "could not generate a unique code", serr.ErrCodeGenerationFailed)
+}
+
+// codeTaken reports whether a gift already has the code. Deleted gifts count too, as they keep their codes until
+// they are purged.
+func (s *Service) codeTaken(code string) (bool, error) {
+	taken, err := s.gift.ExistingCodes([]string{code})
+	if err != nil {
+		return false, err
+	}
+	return taken[code], nil
+}
+
+// codePattern parses the code pattern of the request, if it has one, and checks that the pattern still allows
+// requested more codes next to the gifts already created from it.
+func (s *Service) codePattern(r *CreateRequest, requested int64) (*codegen.Pattern, error) {
+	if r.Pattern == "" {
+		return nil, nil
+	}
+	if r.Code != "" {
+		return nil, serr.ValidationErr("pattern", "code and pattern cannot both be set", serr.ErrInvalidPattern)
+	}
+	pattern, err := s.codes.ParsePattern(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.gift.CountLike(pattern.Like())
+	if err != nil {
+		return nil, err
+	}
+	return pattern, pattern.Check(used, requested)
+}
+
+// newCode generates a code matching the pattern, or a code with the prefix when there is no pattern.
+func (s *Service) newCode(codePrefix string, pattern *codegen.Pattern) (string, error) {
+	if pattern != nil {
+		return pattern.Generate()
+	}
+	return s.codes.Generate(codePrefix)
 }
 
-func (s *Service) generateCode(prefix string) string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	timestamp := time.Now().UnixNano() / int64(time.Microsecond)
-	return fmt.Sprintf("%s-%06d", prefix, timestamp%1e6)
+func isErrorCode(err error, code serr.ErrorCode) bool {
+	var e *serr.ServiceError
+	return errors.As(err, &e) && e.ErrorCode == code
 }
@@ -0,0 +1,70 @@
+package gift_test
+
+import (
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/serr"
+	auditService "discount/service/audit"
+	giftService "discount/service/gift"
+	"discount/storage/audit"
+	"discount/storage/campaign"
+	"discount/storage/gift"
+	"discount/storage/redemption"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestCreateWithDeletedCode(t *testing.T) {
+	service, storage := setup()
+	actor := auditService.Actor{ID: "test"}
+
+	r := &giftService.CreateRequest{Code: "DELETED", GiftAmount: 100, UsageLimit: 10}
+	_, err := service.Create(actor, r)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = service.DeleteByCode(actor, r.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = service.Create(actor, r)
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrCodeAlreadyExists {
+		t.Fatalf("expected %v, got %v", serr.ErrCodeAlreadyExists, err)
+	}
+
+	_, err = storage.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func setup() (*giftService.Service, *gift.Storage) {
+	// Changes are made in transactions, which run on the database opened by NewPostgres.
+	postgres, err := db.NewPostgres("test", "arv123", "asd123ASD", "localhost", "5432", 10, 10)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	codes, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storage := gift.New(postgres, redisClient)
+	// Deleting only hides gifts, so purge the ones earlier tests left behind to free their codes.
+	_, _ = storage.PurgeDeleted(time.Now().Add(time.Minute))
+
+	service := giftService.New(storage, redemption.New(postgres), campaign.New(postgres), codes,
+		auditService.New(audit.New(postgres)))
+	return service, &storage
+}
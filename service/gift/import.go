@@ -0,0 +1,49 @@
+package gift
+
+import (
+	"discount/internal/serr"
+	"discount/storage/gift"
+)
+
+// ImportRequest is a gift moved over from another system. Unlike a create request it must carry its code, and it
+// keeps how far the gift has already been used.
+type ImportRequest struct {
+	CreateRequest
+	UsedCount int64 `json:"usedCount"`
+	// RemainingBalance is what is left of a balance mode gift. It defaults to the whole gift amount.
+	RemainingBalance *int64 `json:"remainingBalance"`
+}
+
+// FromImportRequest validates an imported gift and returns the gift to insert. The code is not checked against
+// existing gifts, and the campaign is not checked either.
+func (s *Service) FromImportRequest(r *ImportRequest) (*gift.Gift, error) {
+	if r.Code == "" {
+		return nil, serr.ValidationErr("code", "code is required", serr.ErrInvalidImportRecord)
+	}
+	err := s.codes.Check(r.Code)
+	if err != nil {
+		return nil, err
+	}
+	if r.GiftAmount <= 0 {
+		return nil, serr.ValidationErr("giftAmount", "invalid amount", serr.ErrInvalidAmount)
+	}
+	if r.UsedCount < 0 || (r.UsageLimit > 0 && r.UsedCount > r.UsageLimit) {
+		return nil, serr.ValidationErr("usedCount", "invalid used count", serr.ErrInvalidImportRecord)
+	}
+	if err = r.Schedule.Validate(); err != nil {
+		return nil, err
+	}
+
+	g, err := s.FromCreateRequest(&r.CreateRequest)
+	if err != nil {
+		return nil, err
+	}
+	g.UsedCount = r.UsedCount
+	if g.BalanceMode && r.RemainingBalance != nil {
+		if *r.RemainingBalance < 0 || *r.RemainingBalance > g.GiftAmount {
+			return nil, serr.ValidationErr("remainingBalance", "invalid remaining balance", serr.ErrInvalidImportRecord)
+		}
+		g.RemainingBalance = *r.RemainingBalance
+	}
+	return g, nil
+}
@@ -0,0 +1,112 @@
+package gift
+
+import (
+	"discount/internal/config"
+	"discount/internal/serr"
+	"discount/storage/gift"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ReserveRequest describes the order a gift usage is held for. TTLSeconds defaults to the configured
+// reservation TTL when it is zero.
+type ReserveRequest struct {
+	UserID     string `json:"userId"`
+	OrderID    string `json:"orderId"`
+	Amount     int64  `json:"amount"`
+	TTLSeconds int64  `json:"ttlSeconds"`
+}
+
+type ReservationDTO struct {
+	ID        string    `json:"id"`
+	Code      string    `json:"code"`
+	UserID    string    `json:"userId"`
+	OrderID   string    `json:"orderId"`
+	Amount    int64     `json:"amount"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Reserve holds one usage of the gift for a checkout. The hold counts toward the usage limit until it is
+// confirmed, cancelled or expires.
+func (s *Service) Reserve(code string, r *ReserveRequest) (*ReservationDTO, error) {
+	ttl := config.ReservationTTL()
+	if r.TTLSeconds != 0 {
+		ttl = time.Duration(r.TTLSeconds) * time.Second
+	}
+	if ttl <= 0 || ttl > config.ReservationMaxTTL() {
+		return nil, serr.ValidationErr("ttlSeconds", "invalid reservation ttl", serr.ErrInvalidReservationTTL)
+	}
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &gift.Reservation{
+		ID:        uuid.NewString(),
+		Code:      code,
+		UserID:    r.UserID,
+		OrderID:   r.OrderID,
+		Amount:    r.Amount,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	_, err = s.gift.Reserve(reservation)
+	if err != nil {
+		return nil, err
+	}
+	return s.fromReservation(reservation), nil
+}
+
+// ConfirmReservation uses the gift usage held by the reservation and records the redemption. The used amount is
+// charged to the campaign budget when the reservation is confirmed, not when it is made. If the redemption cannot
+// be recorded, the usage is given back and the reservation is released.
+func (s *Service) ConfirmReservation(id string) (*DTO, error) {
+	held, err := s.gift.GetReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.gift.GetByCode(held.Code)
+	if err != nil {
+		return nil, err
+	}
+	amount := usedAmount(current.GiftAmount, held.Amount)
+	err = s.spend(current, amount)
+	if err != nil {
+		return nil, err
+	}
+	reservation, g, err := s.gift.ConfirmReservation(id)
+	if err != nil {
+		s.refund(current, amount)
+		return nil, err
+	}
+	result := s.FromDBModel(g)
+	err = s.recordRedemption(result, &UseRequest{
+		UserID:  reservation.UserID,
+		OrderID: reservation.OrderID,
+		Amount:  reservation.Amount,
+	})
+	if err != nil {
+		s.refund(current, amount)
+		return nil, err
+	}
+	return result, nil
+}
+
+// CancelReservation releases the gift usage held by the reservation.
+func (s *Service) CancelReservation(id string) (*ReservationDTO, error) {
+	reservation, err := s.gift.CancelReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.fromReservation(reservation), nil
+}
+
+func (s *Service) fromReservation(r *gift.Reservation) *ReservationDTO {
+	return &ReservationDTO{
+		ID:        r.ID,
+		Code:      r.Code,
+		UserID:    r.UserID,
+		OrderID:   r.OrderID,
+		Amount:    r.Amount,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
@@ -1,26 +1,43 @@
 package gift
 
 import (
+	"context"
 	"database/sql"
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/date"
+	"discount/internal/serr"
+	"discount/service/audit"
+	"discount/storage/campaign"
 	"discount/storage/gift"
+	"discount/storage/redemption"
 	"github.com/jasonlvhit/gocron"
 	"github.com/rs/zerolog/log"
-	"sync"
-	"time"
 )
 
 type Service struct {
-	gift gift.Storage
-	mu   sync.Mutex
+	gift       gift.Storage
+	redemption redemption.Storage
+	campaign   campaign.Storage
+	codes      *codegen.Generator
+	audit      *audit.Service
 
 	inTx bool
 }
 
 func New(
 	gift gift.Storage,
+	redemption redemption.Storage,
+	campaign campaign.Storage,
+	codes *codegen.Generator,
+	audit *audit.Service,
 ) *Service {
 	s := &Service{
-		gift: gift,
+		gift:       gift,
+		redemption: redemption,
+		campaign:   campaign,
+		codes:      codes,
+		audit:      audit,
 	}
 	err := gocron.Every(30).Seconds().Do(func() {
 		if err := s.syncGift(); err != nil {
@@ -29,8 +46,14 @@ func New(
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to start gocron for sync gifts")
-	} else {
-		gocron.Start()
+	}
+	err = gocron.Every(1).Hour().Do(func() {
+		if err := s.purgeDeleted(); err != nil {
+			log.Error().Err(err).Msg("failed to purge deleted gifts")
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start gocron for purging deleted gifts")
 	}
 	return s
 }
@@ -42,46 +65,140 @@ func (s *Service) withTX(tx *sql.Tx) (*Service, error) {
 		return nil, err
 	}
 	service.gift = g
+	r, err := s.redemption.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.redemption = r
+	c, err := s.campaign.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.campaign = c
+	a, err := s.audit.WithTX(tx)
+	if err != nil {
+		return nil, err
+	}
+	service.audit = a
 	service.inTx = true
 	return &service, nil
 }
 
+// transaction runs fn on a service bound to a new database transaction, so a change and its audit entry are
+// committed together.
+func (s *Service) transaction(fn func(service *Service) error) error {
+	return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		service, err := s.withTX(tx)
+		if err != nil {
+			return err
+		}
+		return fn(service)
+	})
+}
+
+// change is transaction for changes of a gift that may be cached, which is evicted from Redis once the
+// transaction commits.
+func (s *Service) change(code string, fn func(service *Service) error) error {
+	return s.gift.Evict(code, func() error {
+		return s.transaction(fn)
+	})
+}
+
 func (s *Service) ToDBModel(g *DTO) *gift.Gift {
 	return &gift.Gift{
-		ID:             g.ID,
-		Code:           g.Code,
-		GiftAmount:     g.GiftAmount,
-		UsageLimit:     g.UsageLimit,
-		UsedCount:      g.UsedCount,
-		ExpirationDate: g.ExpirationDate,
-		StartDateTime:  g.StartDateTime,
+		ID:                    g.ID,
+		Code:                  g.Code,
+		GiftAmount:            g.GiftAmount,
+		UsageLimit:            g.UsageLimit,
+		UsedCount:             g.UsedCount,
+		ExpirationDate:        g.ExpirationDate,
+		StartDateTime:         g.StartDateTime,
+		BalanceMode:           g.BalanceMode,
+		RemainingBalance:      g.RemainingBalance,
+		Exclusive:             g.Exclusive,
+		StackableWithGift:     g.StackableWithGift,
+		StackableWithDiscount: g.StackableWithDiscount,
+		CampaignID:            g.CampaignID,
+		Schedule:              g.Schedule,
+		Status:                g.Status,
+		Version:               g.Version,
 	}
 
 }
 
 func (s *Service) FromDBModel(g *gift.Gift) *DTO {
 	return &DTO{
-		ID:             g.ID,
-		Code:           g.Code,
-		GiftAmount:     g.GiftAmount,
-		UsageLimit:     g.UsageLimit,
-		UsedCount:      g.UsedCount,
-		ExpirationDate: g.ExpirationDate,
-		StartDateTime:  g.StartDateTime,
-		CreatedAt:      g.CreatedAt,
-		UpdatedAt:      g.UpdatedAt,
+		ID:                    g.ID,
+		Code:                  g.Code,
+		GiftAmount:            g.GiftAmount,
+		UsageLimit:            g.UsageLimit,
+		UsedCount:             g.UsedCount,
+		ExpirationDate:        g.ExpirationDate,
+		StartDateTime:         g.StartDateTime,
+		BalanceMode:           g.BalanceMode,
+		RemainingBalance:      g.RemainingBalance,
+		Exclusive:             g.Exclusive,
+		StackableWithGift:     g.StackableWithGift,
+		StackableWithDiscount: g.StackableWithDiscount,
+		CampaignID:            g.CampaignID,
+		Schedule:              g.Schedule,
+		Status:                g.Status,
+		CreatedAt:             g.CreatedAt,
+		UpdatedAt:             g.UpdatedAt,
+		Version:               g.Version,
 	}
 }
 
-func (s *Service) FromCreateRequest(r *CreateRequest) *gift.Gift {
-	const layout = "2006-01-02"
-	exDate, _ := time.Parse(layout, r.ExpirationDate)
-	stDate, _ := time.Parse(layout, r.StartDateTime)
-	return &gift.Gift{
-		Code:           r.Code,
-		GiftAmount:     r.GiftAmount,
-		UsageLimit:     r.UsageLimit,
-		ExpirationDate: exDate,
-		StartDateTime:  stDate,
+// FromCreateRequest returns the gift described by the request. Dates are RFC3339 timestamps or dates in the
+// business timezone; a malformed date is a validation error.
+func (s *Service) FromCreateRequest(r *CreateRequest) (*gift.Gift, error) {
+	stDate, err := date.Parse("startDateTime", r.StartDateTime)
+	if err != nil {
+		return nil, err
+	}
+	exDate, err := date.Parse("expirationDate", r.ExpirationDate)
+	if err != nil {
+		return nil, err
+	}
+	status, err := initialStatus(r.Status)
+	if err != nil {
+		return nil, err
+	}
+	g := &gift.Gift{
+		Code:                  r.Code,
+		GiftAmount:            r.GiftAmount,
+		UsageLimit:            r.UsageLimit,
+		ExpirationDate:        exDate,
+		StartDateTime:         stDate,
+		BalanceMode:           r.BalanceMode,
+		Exclusive:             r.Exclusive,
+		StackableWithGift:     boolOr(r.StackableWithGift, true),
+		StackableWithDiscount: boolOr(r.StackableWithDiscount, true),
+		CampaignID:            r.CampaignID,
+		Schedule:              r.Schedule,
+		Status:                status,
+	}
+	if g.BalanceMode {
+		g.RemainingBalance = g.GiftAmount
+	}
+	return g, nil
+}
+
+// initialStatus is the status a gift is created with: active, unless the request asks for a draft.
+func initialStatus(status string) (string, error) {
+	switch status {
+	case "":
+		return gift.StatusActive, nil
+	case gift.StatusDraft, gift.StatusActive:
+		return status, nil
+	}
+	return "", serr.ValidationErr("status", "invalid status", serr.ErrInvalidStatus)
+}
+
+// boolOr returns the value p points to, or def when p is nil.
+func boolOr(p *bool, def bool) bool {
+	if p == nil {
+		return def
 	}
+	return *p
 }
@@ -0,0 +1,45 @@
+package gift
+
+import (
+	"discount/service/audit"
+	"discount/storage/gift"
+)
+
+// Pause stops the gift from being used until it is resumed.
+func (s *Service) Pause(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionPause, code, gift.StatusPaused)
+}
+
+// Resume makes a paused or draft gift usable again.
+func (s *Service) Resume(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionResume, code, gift.StatusActive)
+}
+
+// Archive retires the gift for good. Unlike deleting it, its redemptions and history are kept.
+func (s *Service) Archive(actor audit.Actor, code string) (*DTO, error) {
+	return s.setStatus(actor, audit.ActionArchive, code, gift.StatusArchived)
+}
+
+func (s *Service) setStatus(actor audit.Actor, action, code, status string) (*DTO, error) {
+	err := s.codes.Check(code)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.gift.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	var result *DTO
+	err = s.change(code, func(service *Service) error {
+		g, err := service.gift.SetStatus(code, status)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(g)
+		return service.audit.Record(actor, action, audit.EntityGift, code, s.FromDBModel(current), result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package idempotency
+
+import (
+	"discount/internal/config"
+	"discount/internal/serr"
+	"discount/storage/idempotency"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// lockTTL bounds how long a request holds its idempotency key, so a crashed request does not block the key forever.
+const lockTTL = 30 * time.Second
+
+const maxKeyLength = 255
+
+// ResponseDTO is a response stored for an idempotency key.
+type ResponseDTO struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Begin starts a request made with an idempotency key. request is a hash of the request, such as of its body. If a
+// response has already been stored for the key within the retention window, it is returned and the request must not
+// be executed again; a key already used for a different request is refused. Otherwise the key is locked until
+// Complete is called, and a concurrent request with the same key is refused.
+func (s *Service) Begin(scope, key, request string) (*ResponseDTO, error) {
+	if len(key) > maxKeyLength {
+		return nil, serr.ValidationErr("Begin", "invalid idempotency key", serr.ErrInvalidIdempotencyKey)
+	}
+	r, err := s.idempotency.Get(scope, key, config.IdempotencyRetention())
+	if err != nil {
+		return nil, serr.DBError("Begin", "idempotency key", err)
+	}
+	if r != nil {
+		return replay(r, request)
+	}
+
+	ok, err := s.idempotency.Lock(scope, key, lockTTL)
+	if err != nil {
+		return nil, serr.DBError("Begin", "idempotency key", err)
+	}
+	if !ok {
+		return nil, serr.ConflictErr("Begin", "a request with this idempotency key is already in progress",
+			serr.ErrIdempotencyKeyInProgress)
+	}
+
+	// A request with the same key may have completed and released the lock between the check above and taking
+	// the lock, so its response is looked up again before the request is executed.
+	r, err = s.idempotency.Get(scope, key, config.IdempotencyRetention())
+	if err != nil {
+		s.idempotency.Unlock(scope, key)
+		return nil, serr.DBError("Begin", "idempotency key", err)
+	}
+	if r != nil {
+		s.idempotency.Unlock(scope, key)
+		return replay(r, request)
+	}
+	return nil, nil
+}
+
+// Complete stores a successful response for the key and releases it. Failed responses are not stored, so the
+// client can retry them with the same key. When a successful response cannot be stored, the key is left locked
+// until the lock expires, so a retry cannot run the request again right away.
+func (s *Service) Complete(scope, key, request string, statusCode int, body []byte) {
+	if statusCode < 200 || statusCode >= 300 {
+		s.idempotency.Unlock(scope, key)
+		return
+	}
+	err := s.idempotency.Save(&idempotency.Record{
+		Key:        key,
+		Scope:      scope,
+		Request:    request,
+		StatusCode: statusCode,
+		Response:   body,
+	}, config.IdempotencyRetention())
+	if err != nil {
+		log.Error().Err(err).Str("scope", scope).Msg("failed to store idempotent response")
+		return
+	}
+	s.idempotency.Unlock(scope, key)
+}
+
+// replay returns the stored response, provided it was stored for the same request.
+func replay(r *idempotency.Record, request string) (*ResponseDTO, error) {
+	if r.Request != request {
+		return nil, serr.ConflictErr("Begin", "idempotency key was used with a different request",
+			serr.ErrIdempotencyKeyReused)
+	}
+	return &ResponseDTO{StatusCode: r.StatusCode, Body: r.Response}, nil
+}
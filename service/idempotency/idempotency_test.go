@@ -0,0 +1,79 @@
+package idempotency
+
+import (
+	"discount/internal/serr"
+	"discount/storage/idempotency"
+	"errors"
+	"testing"
+	"time"
+)
+
+// racingStore stores the response of a concurrent request with the same key right before Lock succeeds, as if that
+// request completed and released the key between the first Get and Lock of Begin.
+type racingStore struct {
+	record   *idempotency.Record
+	saveErr  error
+	locked   bool
+	unlocked bool
+}
+
+func (s *racingStore) Get(string, string, time.Duration) (*idempotency.Record, error) {
+	if !s.locked {
+		return nil, nil
+	}
+	return s.record, nil
+}
+
+func (s *racingStore) Save(*idempotency.Record, time.Duration) error { return s.saveErr }
+
+func (s *racingStore) Lock(string, string, time.Duration) (bool, error) {
+	s.locked = true
+	return true, nil
+}
+
+func (s *racingStore) Unlock(string, string) { s.unlocked = true }
+
+func (s *racingStore) DeleteOlderThan(time.Time) error { return nil }
+
+func TestBeginReplaysResponseStoredBeforeLock(t *testing.T) {
+	store := &racingStore{record: &idempotency.Record{Request: "A", StatusCode: 201, Response: []byte(`{"id":1}`)}}
+	s := &Service{idempotency: store}
+
+	r, err := s.Begin("gift", "KEY", "A")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if r == nil || r.StatusCode != 201 || string(r.Body) != `{"id":1}` {
+		t.Fatalf("expected the stored response, got %+v", r)
+	}
+	if !store.unlocked {
+		t.Fatalf("expected the key to be released")
+	}
+}
+
+func TestBeginRefusesKeyReusedForAnotherRequest(t *testing.T) {
+	store := &racingStore{record: &idempotency.Record{Request: "A", StatusCode: 201}, locked: true}
+	s := &Service{idempotency: store}
+
+	_, err := s.Begin("gift", "KEY", "B")
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrIdempotencyKeyReused {
+		t.Fatalf("expected %v, got %v", serr.ErrIdempotencyKeyReused, err)
+	}
+}
+
+func TestCompleteKeepsLockWhenResponseIsNotStored(t *testing.T) {
+	store := &racingStore{saveErr: errors.New("database is down")}
+	s := &Service{idempotency: store}
+
+	s.Complete("gift", "KEY", "A", 200, []byte(`{}`))
+	if store.unlocked {
+		t.Fatalf("expected the key to stay locked")
+	}
+
+	store.saveErr = nil
+	s.Complete("gift", "KEY", "A", 200, []byte(`{}`))
+	if !store.unlocked {
+		t.Fatalf("expected the key to be released")
+	}
+}
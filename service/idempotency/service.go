@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"discount/internal/config"
+	"discount/storage/idempotency"
+	"github.com/jasonlvhit/gocron"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type Service struct {
+	idempotency store
+}
+
+// store is the part of idempotency.Storage the service uses.
+type store interface {
+	Get(scope, key string, retention time.Duration) (*idempotency.Record, error)
+	Save(r *idempotency.Record, retention time.Duration) error
+	Lock(scope, key string, ttl time.Duration) (bool, error)
+	Unlock(scope, key string)
+	DeleteOlderThan(t time.Time) error
+}
+
+func New(
+	idempotency idempotency.Storage,
+) *Service {
+	s := &Service{
+		idempotency: idempotency,
+	}
+	err := gocron.Every(1).Hour().Do(func() {
+		if err := s.cleanup(); err != nil {
+			log.Error().Err(err).Msg("failed to clean up idempotency keys")
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start gocron for idempotency keys cleanup")
+	}
+	return s
+}
+
+// cleanup removes the stored responses that are older than the retention window.
+func (s *Service) cleanup() error {
+	return s.idempotency.DeleteOlderThan(time.Now().Add(-config.IdempotencyRetention()))
+}
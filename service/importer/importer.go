@@ -0,0 +1,307 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/config"
+	"discount/internal/serr"
+	"discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const (
+	TypeGift     = "gift"
+	TypeDiscount = "discount"
+
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// batchSize is how many records are inserted per statement.
+const batchSize = 1000
+
+// Request describes an import file. In a dry run the file is only checked and nothing is written.
+type Request struct {
+	Type   string `form:"-"`
+	Format string `form:"format"`
+	DryRun bool   `form:"dryRun"`
+}
+
+// LineErrorDTO tells why a line of an import file cannot be imported. Field is the field or column at fault.
+type LineErrorDTO struct {
+	Line    int                `json:"line"`
+	Code    string             `json:"code"`
+	Field   string             `json:"field"`
+	Failure *serr.ServiceError `json:"-"`
+}
+
+// ReportDTO is the outcome of an import. Codes are only imported when every record of the file is valid, so
+// Imported is zero whenever Errors is not empty, and always zero in a dry run.
+type ReportDTO struct {
+	Type     string          `json:"type"`
+	DryRun   bool            `json:"dryRun"`
+	Records  int             `json:"records"`
+	Valid    int             `json:"valid"`
+	Imported int64           `json:"imported"`
+	Errors   []*LineErrorDTO `json:"errors"`
+}
+
+// record is a valid record waiting to be inserted.
+type record struct {
+	line       int
+	code       string
+	campaignID *int64
+}
+
+// Import reads gifts or discounts from a CSV or JSON Lines file and, unless the request is a dry run, inserts
+// them together with their used counts. Every record is checked first, including whether its code is already
+// taken, and nothing is written unless all of them are valid. An import that writes codes is recorded in the audit
+// log with its report.
+func (s *Service) Import(actor audit.Actor, r *Request, in io.Reader) (*ReportDTO, error) {
+	records, err := newRecordReader(r.Format, in)
+	if err != nil {
+		return nil, err
+	}
+	report := &ReportDTO{Type: r.Type, DryRun: r.DryRun, Errors: make([]*LineErrorDTO, 0)}
+	switch r.Type {
+	case TypeGift:
+		err = s.importGifts(actor, records, report)
+	case TypeDiscount:
+		err = s.importDiscounts(actor, records, report)
+	default:
+		err = serr.ValidationErr("type", "invalid code type", serr.ErrInvalidCodeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+	return report, nil
+}
+
+func (s *Service) importGifts(actor audit.Actor, records recordReader, report *ReportDTO) error {
+	var gifts []*giftStorage.Gift
+	newRecord := func() any { return &giftService.ImportRequest{} }
+	valid, err := s.read(records, report, newRecord, func(v any) (*record, error) {
+		g, err := s.giftService.FromImportRequest(v.(*giftService.ImportRequest))
+		if err != nil {
+			return nil, err
+		}
+		gifts = append(gifts, g)
+		return &record{code: g.Code, campaignID: g.CampaignID}, nil
+	})
+	if err != nil {
+		return err
+	}
+	taken, err := s.existingCodes(valid, s.gift.ExistingCodes)
+	if err != nil {
+		return err
+	}
+	s.check(valid, taken, report)
+	if report.DryRun || len(report.Errors) > 0 {
+		return nil
+	}
+
+	return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		gs, err := s.gift.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(gifts); start += batchSize {
+			batch := gifts[start:min(start+batchSize, len(gifts))]
+			n, err := gs.InsertBatch(batch)
+			if err != nil {
+				return err
+			}
+			if err = inserted(n, len(batch)); err != nil {
+				return err
+			}
+			report.Imported += n
+		}
+		return s.record(tx, actor, report)
+	})
+}
+
+func (s *Service) importDiscounts(actor audit.Actor, records recordReader, report *ReportDTO) error {
+	var discounts []*discountStorage.Discount
+	newRecord := func() any { return &discountService.ImportRequest{} }
+	valid, err := s.read(records, report, newRecord, func(v any) (*record, error) {
+		d, err := s.discountService.FromImportRequest(v.(*discountService.ImportRequest))
+		if err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, d)
+		return &record{code: d.Code, campaignID: d.CampaignID}, nil
+	})
+	if err != nil {
+		return err
+	}
+	taken, err := s.existingCodes(valid, s.discount.ExistingCodes)
+	if err != nil {
+		return err
+	}
+	s.check(valid, taken, report)
+	if report.DryRun || len(report.Errors) > 0 {
+		return nil
+	}
+
+	return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		ds, err := s.discount.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(discounts); start += batchSize {
+			batch := discounts[start:min(start+batchSize, len(discounts))]
+			n, err := ds.InsertBatch(batch)
+			if err != nil {
+				return err
+			}
+			if err = inserted(n, len(batch)); err != nil {
+				return err
+			}
+			report.Imported += n
+		}
+		return s.record(tx, actor, report)
+	})
+}
+
+// record adds the import to the audit log in the transaction that inserts its codes.
+func (s *Service) record(tx *sql.Tx, actor audit.Actor, report *ReportDTO) error {
+	if report.Imported == 0 {
+		return nil
+	}
+	as, err := s.audit.WithTX(tx)
+	if err != nil {
+		return err
+	}
+	return as.Record(actor, audit.ActionImport, report.Type, "", nil, report)
+}
+
+// read decodes every record of the file into a value made by newRecord and validates it with validate. Records
+// that fail are added to the report and the valid ones are returned.
+func (s *Service) read(
+	records recordReader, report *ReportDTO, newRecord func() any, validate func(v any) (*record, error),
+) ([]*record, error) {
+	var valid []*record
+	maxRecords := config.ImportMaxRecords()
+	for {
+		v := newRecord()
+		line, err := records.next(v)
+		if errors.Is(err, io.EOF) {
+			return valid, nil
+		}
+		report.Records++
+		if maxRecords > 0 && report.Records > maxRecords {
+			return nil, serr.ValidationErr("file", "too many records in import file", serr.ErrInvalidImport)
+		}
+		if err == nil {
+			var rec *record
+			rec, err = validate(v)
+			if err == nil {
+				rec.line = line
+				valid = append(valid, rec)
+				continue
+			}
+		}
+		failure, err := lineFailure(err)
+		if err != nil {
+			return nil, err
+		}
+		report.Errors = append(report.Errors, &LineErrorDTO{Line: line, Code: codeOf(v), Field: failure.Method,
+			Failure: failure})
+	}
+}
+
+// check reports valid records whose code is taken, given more than once or assigned to an unknown campaign, and
+// counts the records that remain valid.
+func (s *Service) check(valid []*record, taken map[string]bool, report *ReportDTO) {
+	seen := make(map[string]bool, len(valid))
+	campaigns := make(map[int64]bool)
+	for _, r := range valid {
+		var err error
+		switch {
+		case taken[r.code]:
+			err = serr.ValidationErr("code", "code already exists", serr.ErrCodeAlreadyExists)
+		case seen[r.code]:
+			err = serr.ValidationErr("code", "code is given more than once", serr.ErrDuplicateCode)
+		case r.campaignID != nil && !s.campaignExists(*r.campaignID, campaigns):
+			err = serr.ValidationErr("campaignId", "invalid campaign", serr.ErrInvalidCampaign)
+		}
+		seen[r.code] = true
+		if err != nil {
+			failure, _ := lineFailure(err)
+			report.Errors = append(report.Errors, &LineErrorDTO{Line: r.line, Code: r.code, Field: failure.Method,
+				Failure: failure})
+			continue
+		}
+		report.Valid++
+	}
+}
+
+// campaignExists tells whether the campaign exists, remembering the answer in known.
+func (s *Service) campaignExists(id int64, known map[int64]bool) bool {
+	exists, ok := known[id]
+	if !ok {
+		_, err := s.campaign.GetByID(id)
+		exists = err == nil
+		known[id] = exists
+	}
+	return exists
+}
+
+// existingCodes returns which codes of the records are already taken, looking them up batch by batch.
+func (s *Service) existingCodes(
+	records []*record, lookup func(codes []string) (map[string]bool, error),
+) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	for start := 0; start < len(records); start += batchSize {
+		end := min(start+batchSize, len(records))
+		codes := make([]string, 0, end-start)
+		for _, r := range records[start:end] {
+			codes = append(codes, r.code)
+		}
+		existing, err := lookup(codes)
+		if err != nil {
+			return nil, err
+		}
+		for code := range existing {
+			taken[code] = true
+		}
+	}
+	return taken, nil
+}
+
+// inserted fails the import when some codes of a batch were taken between the check and the insert.
+func inserted(n int64, size int) error {
+	if n < int64(size) {
+		return serr.ConflictErr("code", "code already exists", serr.ErrCodeAlreadyExists)
+	}
+	return nil
+}
+
+// lineFailure returns the error that makes a single record fail, or err itself when it should end the import.
+func lineFailure(err error) (*serr.ServiceError, error) {
+	var e *serr.ServiceError
+	if errors.As(err, &e) && e.Code == http.StatusBadRequest && e.ErrorCode != serr.ErrInvalidImport {
+		return e, nil
+	}
+	return nil, err
+}
+
+// codeOf returns the code of a decoded record, as far as it could be decoded.
+func codeOf(v any) string {
+	switch r := v.(type) {
+	case *giftService.ImportRequest:
+		return r.Code
+	case *discountService.ImportRequest:
+		return r.Code
+	}
+	return ""
+}
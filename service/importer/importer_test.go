@@ -0,0 +1,85 @@
+package importer_test
+
+import (
+	"discount/db"
+	"discount/internal/codegen"
+	"discount/internal/serr"
+	auditService "discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	"discount/service/importer"
+	"discount/storage/audit"
+	"discount/storage/campaign"
+	"discount/storage/discount"
+	"discount/storage/gift"
+	"discount/storage/redemption"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestImportDryRun(t *testing.T) {
+	service, storage := setup()
+	actor := auditService.Actor{ID: "test"}
+	r := &importer.Request{Type: importer.TypeGift, Format: importer.FormatCSV, DryRun: true}
+
+	in := "code,gift_amount\nDRYRUN1,100\nDRYRUN2,0\nDRYRUN1,100\n"
+	report, err := service.Import(actor, r, strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Records != 3 || report.Valid != 1 || report.Imported != 0 || len(report.Errors) != 2 {
+		t.Fatalf("expected 3 records, 1 valid, none imported and 2 errors, got %+v", report)
+	}
+	if report.Errors[0].Line != 3 || report.Errors[0].Failure.ErrorCode != serr.ErrInvalidAmount {
+		t.Fatalf("expected line 3 to fail with %v, got %+v", serr.ErrInvalidAmount, report.Errors[0])
+	}
+	if report.Errors[1].Line != 4 || report.Errors[1].Failure.ErrorCode != serr.ErrDuplicateCode {
+		t.Fatalf("expected line 4 to fail with %v, got %+v", serr.ErrDuplicateCode, report.Errors[1])
+	}
+
+	in = "code,giftAmount\nDRYRUN1,100\nDRYRUN2,200\n"
+	report, err = service.Import(actor, r, strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if report.Records != 2 || report.Valid != 2 || report.Imported != 0 || len(report.Errors) != 0 {
+		t.Fatalf("expected 2 valid records and none imported, got %+v", report)
+	}
+	_, err = storage.GetByCode("DRYRUN1")
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrInvalidGiftCode {
+		t.Fatalf("expected %v, got %v", serr.ErrInvalidGiftCode, err)
+	}
+}
+
+func setup() (*importer.Service, *gift.Storage) {
+	// Imports are written in transactions, which run on the database opened by NewPostgres.
+	postgres, err := db.NewPostgres("test", "arv123", "asd123ASD", "localhost", "5432", 10, 10)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	codes, err := codegen.New(codegen.DefaultAlphabet, 10, 4, "-")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	giftStorage := gift.New(postgres, redisClient)
+	discountStorage := discount.New(postgres)
+	redemptionStorage := redemption.New(postgres)
+	campaignStorage := campaign.New(postgres)
+	audits := auditService.New(audit.New(postgres))
+
+	service := importer.New(giftStorage, discountStorage, campaignStorage,
+		giftService.New(giftStorage, redemptionStorage, campaignStorage, codes, audits),
+		discountService.New(discountStorage, redemptionStorage, campaignStorage, codes, audits),
+		audits)
+	return service, &giftStorage
+}
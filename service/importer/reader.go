@@ -0,0 +1,173 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"discount/internal/serr"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxLineSize is the longest line accepted in a JSON Lines file.
+const maxLineSize = 1 << 20
+
+// recordReader reads the records of an import file one by one. next decodes the following record into v and
+// returns its line number; it returns io.EOF after the last record. A record that cannot be decoded is reported
+// with ErrInvalidImportRecord, after which reading can go on with the next record. Any other error ends the import.
+type recordReader interface {
+	next(v any) (int, error)
+}
+
+func newRecordReader(format string, in io.Reader) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		r := csv.NewReader(in)
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = true
+		header, err := r.Read()
+		if err != nil {
+			return nil, serr.ValidationErr("file", "invalid import file", serr.ErrInvalidImport)
+		}
+		return &csvReader{r: r, header: header}, nil
+	case FormatJSONL:
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		return &jsonlReader{sc: sc}, nil
+	default:
+		return nil, serr.ValidationErr("format", "invalid import format", serr.ErrInvalidImport)
+	}
+}
+
+// csvReader reads CSV files with a header row. Columns are matched to the JSON names of the record fields,
+// ignoring case and underscores, so both giftAmount and gift_amount fill GiftAmount. Empty cells are left unset.
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func (c *csvReader) next(v any) (int, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, io.EOF
+		}
+		return 0, serr.ValidationErr("file", "invalid import file", serr.ErrInvalidImport)
+	}
+	line, _ := c.r.FieldPos(0)
+	fields := fieldsByName(reflect.ValueOf(v).Elem())
+	for i, column := range c.header {
+		field, ok := fields[normalizeName(column)]
+		if !ok {
+			return 0, serr.ValidationErr(column, "unknown import column", serr.ErrInvalidImport)
+		}
+		if i >= len(record) || record[i] == "" {
+			continue
+		}
+		if !setField(field, record[i]) {
+			return line, serr.ValidationErr(column, "invalid import record", serr.ErrInvalidImportRecord)
+		}
+	}
+	if len(record) != len(c.header) {
+		return line, serr.ValidationErr("line", "invalid import record", serr.ErrInvalidImportRecord)
+	}
+	return line, nil
+}
+
+// jsonlReader reads JSON Lines files, one JSON object per line. Blank lines are skipped.
+type jsonlReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (j *jsonlReader) next(v any) (int, error) {
+	for j.sc.Scan() {
+		j.line++
+		b := bytes.TrimSpace(j.sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		if err := d.Decode(v); err != nil {
+			field := "line"
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				field = typeErr.Field
+			}
+			return j.line, serr.ValidationErr(field, "invalid import record", serr.ErrInvalidImportRecord)
+		}
+		return j.line, nil
+	}
+	if err := j.sc.Err(); err != nil {
+		return 0, serr.ValidationErr("file", "invalid import file", serr.ErrInvalidImport)
+	}
+	return 0, io.EOF
+}
+
+// fieldsByName maps the normalized JSON names of the fields of a struct, including those of embedded structs,
+// to the fields themselves.
+func fieldsByName(v reflect.Value) map[string]reflect.Value {
+	fields := make(map[string]reflect.Value)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			for name, field := range fieldsByName(v.Field(i)) {
+				fields[name] = field
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[normalizeName(name)] = v.Field(i)
+	}
+	return fields
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", ""))
+}
+
+// setField parses value into a string, int64 or bool field, or a pointer to one, and reports whether it could.
+// Slice fields, such as the schedule of a code, are given as JSON.
+func setField(field reflect.Value, value string) bool {
+	if field.Kind() == reflect.Pointer {
+		p := reflect.New(field.Type().Elem())
+		if !setField(p.Elem(), value) {
+			return false
+		}
+		field.Set(p)
+		return true
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		field.SetInt(i)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		err := json.Unmarshal([]byte(value), field.Addr().Interface())
+		if err != nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package importer
+
+import (
+	"discount/internal/serr"
+	giftService "discount/service/gift"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRecordReader(t *testing.T) {
+	// read is what next returned for one record. errorCode is empty for a record read without error.
+	type read struct {
+		line       int
+		code       string
+		giftAmount int64
+		field      string
+		errorCode  serr.ErrorCode
+	}
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []read
+	}{
+		{
+			name:   "csv with camel case header",
+			format: FormatCSV,
+			input:  "code,giftAmount\nA,100\nB,200\n",
+			want:   []read{{line: 2, code: "A", giftAmount: 100}, {line: 3, code: "B", giftAmount: 200}},
+		},
+		{
+			name:   "csv with snake case header",
+			format: FormatCSV,
+			input:  "Code, gift_amount\nA, 100\n",
+			want:   []read{{line: 2, code: "A", giftAmount: 100}},
+		},
+		{
+			name:   "csv empty cell left unset",
+			format: FormatCSV,
+			input:  "code,giftAmount,usageLimit\nA,,5\n",
+			want:   []read{{line: 2, code: "A"}},
+		},
+		{
+			name:   "csv unknown column",
+			format: FormatCSV,
+			input:  "code,color\nA,red\n",
+			want:   []read{{code: "A", field: "color", errorCode: serr.ErrInvalidImport}},
+		},
+		{
+			name:   "csv short row",
+			format: FormatCSV,
+			input:  "code,giftAmount\nA\nB,200\n",
+			want: []read{
+				{line: 2, code: "A", field: "line", errorCode: serr.ErrInvalidImportRecord},
+				{line: 3, code: "B", giftAmount: 200},
+			},
+		},
+		{
+			name:   "csv long row",
+			format: FormatCSV,
+			input:  "code,giftAmount\nA,100,extra\n",
+			want: []read{
+				{line: 2, code: "A", giftAmount: 100, field: "line", errorCode: serr.ErrInvalidImportRecord},
+			},
+		},
+		{
+			name:   "csv invalid number",
+			format: FormatCSV,
+			input:  "code,giftAmount\nA,many\n",
+			want:   []read{{line: 2, code: "A", field: "giftAmount", errorCode: serr.ErrInvalidImportRecord}},
+		},
+		{
+			name:   "csv line numbers count quoted line breaks",
+			format: FormatCSV,
+			input:  "code,pattern\n\"A\",\"x\ny\"\nB,z\n",
+			want:   []read{{line: 2, code: "A"}, {line: 4, code: "B"}},
+		},
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			input:  "{\"code\":\"A\",\"giftAmount\":100}\n{\"code\":\"B\",\"giftAmount\":200}\n",
+			want:   []read{{line: 1, code: "A", giftAmount: 100}, {line: 2, code: "B", giftAmount: 200}},
+		},
+		{
+			name:   "jsonl blank lines skipped",
+			format: FormatJSONL,
+			input:  "\n{\"code\":\"A\"}\n   \n\n{\"code\":\"B\"}",
+			want:   []read{{line: 2, code: "A"}, {line: 5, code: "B"}},
+		},
+		{
+			name:   "jsonl unknown field",
+			format: FormatJSONL,
+			input:  "{\"code\":\"A\",\"color\":\"red\"}\n{\"code\":\"B\"}\n",
+			want: []read{
+				{line: 1, code: "A", field: "line", errorCode: serr.ErrInvalidImportRecord},
+				{line: 2, code: "B"},
+			},
+		},
+		{
+			name:   "jsonl invalid type",
+			format: FormatJSONL,
+			input:  "{\"code\":\"A\",\"giftAmount\":\"many\"}\n",
+			want:   []read{{line: 1, code: "A", field: "giftAmount", errorCode: serr.ErrInvalidImportRecord}},
+		},
+		{
+			name:   "jsonl invalid json",
+			format: FormatJSONL,
+			input:  "{\"code\":\n",
+			want:   []read{{line: 1, field: "line", errorCode: serr.ErrInvalidImportRecord}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := newRecordReader(tt.format, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			var got []read
+			for {
+				v := &giftService.ImportRequest{}
+				line, err := records.next(v)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				r := read{line: line, code: v.Code, giftAmount: v.GiftAmount}
+				var e *serr.ServiceError
+				if err != nil && !errors.As(err, &e) {
+					t.Fatalf("expected a service error, got %v", err)
+				}
+				if e != nil {
+					r.field, r.errorCode = e.Method, e.ErrorCode
+				}
+				got = append(got, r)
+				if r.errorCode == serr.ErrInvalidImport {
+					break
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected record %d to be %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecordReaderInvalidFormat(t *testing.T) {
+	_, err := newRecordReader("xml", strings.NewReader(""))
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrInvalidImport {
+		t.Fatalf("expected %v, got %v", serr.ErrInvalidImport, err)
+	}
+}
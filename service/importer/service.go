@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	campaignStorage "discount/storage/campaign"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+)
+
+type Service struct {
+	gift            giftStorage.Storage
+	discount        discountStorage.Storage
+	campaign        campaignStorage.Storage
+	giftService     *giftService.Service
+	discountService *discountService.Service
+	audit           *audit.Service
+}
+
+func New(
+	gift giftStorage.Storage,
+	discount discountStorage.Storage,
+	campaign campaignStorage.Storage,
+	giftService *giftService.Service,
+	discountService *discountService.Service,
+	audit *audit.Service,
+) *Service {
+	return &Service{
+		gift:            gift,
+		discount:        discount,
+		campaign:        campaign,
+		giftService:     giftService,
+		discountService: discountService,
+		audit:           audit,
+	}
+}
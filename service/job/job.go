@@ -0,0 +1,251 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/config"
+	"discount/internal/serr"
+	"discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"discount/storage/job"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	TypeGift     = "gift"
+	TypeDiscount = "discount"
+)
+
+// maxStalls is how many batches in a row may fail to create any code before a job gives up.
+const maxStalls = 10
+
+// CreateRequest asks for Count codes of the given type, all created from the gift or discount template matching
+// the type. Prefix and Pattern replace the code prefix and code pattern of the template.
+type CreateRequest struct {
+	Type     string                         `json:"type"`
+	Count    int64                          `json:"count"`
+	Prefix   string                         `json:"prefix"`
+	Pattern  string                         `json:"pattern"`
+	Gift     *giftService.CreateRequest     `json:"gift"`
+	Discount *discountService.CreateRequest `json:"discount"`
+}
+
+// DTO is the status of a bulk generation job. Progress is the percentage of the codes generated so far. Status is
+// pending, running, completed or failed; a job interrupted before it finished is marked failed rather than resumed.
+type DTO struct {
+	ID         int64      `json:"id"`
+	Type       string     `json:"type"`
+	Prefix     string     `json:"prefix"`
+	Count      int64      `json:"count"`
+	Generated  int64      `json:"generated"`
+	Progress   float64    `json:"progress"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+}
+
+// Create records a bulk generation job and starts generating its codes in the background. The returned job is
+// pending; its progress can be followed with GetByID.
+func (s *Service) Create(actor audit.Actor, r *CreateRequest) (*DTO, error) {
+	template, err := s.template(r)
+	if err != nil {
+		return nil, err
+	}
+	j := &job.Job{
+		CodeType: r.Type,
+		Prefix:   r.Prefix,
+		Count:    r.Count,
+		Status:   job.StatusPending,
+		Template: template,
+	}
+	var result *DTO
+	err = db.Transaction(context.Background(), func(tx *sql.Tx) error {
+		js, err := s.job.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		as, err := s.audit.WithTX(tx)
+		if err != nil {
+			return err
+		}
+		err = js.Create(j)
+		if err != nil {
+			return err
+		}
+		result = s.FromDBModel(j)
+		return as.Record(actor, audit.ActionBulkCreate, audit.EntityJob, itoa(j.ID), nil, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	go s.run(j)
+	return result, nil
+}
+
+func (s *Service) GetByID(id int64) (*DTO, error) {
+	j, err := s.job.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.FromDBModel(j), nil
+}
+
+// WriteCSV writes the codes generated by a completed job to w as CSV. The codes are streamed from the database,
+// so nothing is written when the job cannot be downloaded.
+func (s *Service) WriteCSV(id int64, w io.Writer) error {
+	j, err := s.job.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if j.Status != job.StatusCompleted {
+		return serr.ConflictErr("id", "job has not completed", serr.ErrJobNotFinished)
+	}
+
+	cw := csv.NewWriter(w)
+	if j.CodeType == TypeGift {
+		err = cw.Write([]string{"code", "gift_amount", "usage_limit", "start_date_time", "expiration_date"})
+		if err == nil {
+			err = s.gift.ForEachByJob(id, func(g *giftStorage.Gift) error {
+				return cw.Write([]string{g.Code, itoa(g.GiftAmount), itoa(g.UsageLimit), formatTime(g.StartDateTime),
+					formatTime(g.ExpirationDate)})
+			})
+		}
+	} else {
+		err = cw.Write([]string{"code", "percent_off", "discount_amount", "max_amount", "min_amount", "usage_limit",
+			"start_date_time", "expiration_date"})
+		if err == nil {
+			err = s.discount.ForEachByJob(id, func(d *discountStorage.Discount) error {
+				return cw.Write([]string{d.Code, itoa(d.PercentOff), itoa(d.DiscountAmount), itoa(d.MaxAmount),
+					itoa(d.MinAmount), itoa(d.UsageLimit), formatTime(d.StartDateTime), formatTime(d.ExpirationDate)})
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// template validates the request and returns the template the codes of the job are created from.
+func (s *Service) template(r *CreateRequest) ([]byte, error) {
+	if r.Count <= 0 || r.Count > config.JobMaxCount() {
+		return nil, serr.ValidationErr("count", "invalid job count", serr.ErrInvalidJob)
+	}
+	switch r.Type {
+	case TypeGift:
+		if r.Gift == nil || r.Gift.Code != "" {
+			return nil, serr.ValidationErr("gift", "invalid job template", serr.ErrInvalidJob)
+		}
+		r.Gift.CodePrefix, r.Gift.Pattern = r.Prefix, r.Pattern
+		if err := s.giftService.ValidateTemplate(r.Gift, r.Count); err != nil {
+			return nil, err
+		}
+		return json.Marshal(r.Gift)
+	case TypeDiscount:
+		if r.Discount == nil || r.Discount.Code != "" {
+			return nil, serr.ValidationErr("discount", "invalid job template", serr.ErrInvalidJob)
+		}
+		r.Discount.CodePrefix, r.Discount.Pattern = r.Prefix, r.Pattern
+		if err := s.discountService.ValidateTemplate(r.Discount, r.Count); err != nil {
+			return nil, err
+		}
+		return json.Marshal(r.Discount)
+	default:
+		return nil, serr.ValidationErr("type", "invalid code type", serr.ErrInvalidCodeType)
+	}
+}
+
+// run generates the codes of the job and records how it ended.
+func (s *Service) run(j *job.Job) {
+	status, msg := job.StatusCompleted, ""
+	err := s.generate(j)
+	if err != nil {
+		log.Error().Err(err).Int64("job", j.ID).Msg("bulk generation job failed")
+		status, msg = job.StatusFailed, err.Error()
+	}
+	err = s.job.Finish(j, status, msg)
+	if errors.Is(err, job.ErrJobFinished) {
+		log.Warn().Int64("job", j.ID).Msg("bulk generation job had already finished")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Int64("job", j.ID).Msg("failed to finish bulk generation job")
+	}
+}
+
+// generate creates the codes of the job batch by batch and records the progress after each batch.
+func (s *Service) generate(j *job.Job) error {
+	createBatch, err := s.batchCreator(j)
+	if err != nil {
+		return err
+	}
+	batchSize := int64(config.JobBatchSize())
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	stalls := 0
+	for j.Generated < j.Count {
+		n := j.Count - j.Generated
+		if n > batchSize {
+			n = batchSize
+		}
+		created, err := createBatch(int(n))
+		if err != nil {
+			return err
+		}
+		if created == 0 {
+			stalls++
+			if stalls >= maxStalls {
+				return errors.New("could not generate enough unique codes")
+			}
+			continue
+		}
+		stalls = 0
+		j.Generated += created
+		err = s.job.UpdateProgress(j)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchCreator returns a function creating a batch of codes from the template of the job.
+func (s *Service) batchCreator(j *job.Job) (func(n int) (int64, error), error) {
+	if j.CodeType == TypeGift {
+		var r giftService.CreateRequest
+		if err := json.Unmarshal(j.Template, &r); err != nil {
+			return nil, err
+		}
+		return func(n int) (int64, error) { return s.giftService.CreateBatch(&r, j.ID, n) }, nil
+	}
+	var r discountService.CreateRequest
+	if err := json.Unmarshal(j.Template, &r); err != nil {
+		return nil, err
+	}
+	return func(n int) (int64, error) { return s.discountService.CreateBatch(&r, j.ID, n) }, nil
+}
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,86 @@
+package job
+
+import (
+	"discount/service/audit"
+	discountService "discount/service/discount"
+	giftService "discount/service/gift"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"discount/storage/job"
+	"github.com/jasonlvhit/gocron"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type Service struct {
+	job             job.Storage
+	gift            giftStorage.Storage
+	discount        discountStorage.Storage
+	giftService     *giftService.Service
+	discountService *discountService.Service
+	audit           *audit.Service
+}
+
+func New(
+	job job.Storage,
+	gift giftStorage.Storage,
+	discount discountStorage.Storage,
+	giftService *giftService.Service,
+	discountService *discountService.Service,
+	audit *audit.Service,
+) *Service {
+	s := &Service{
+		job:             job,
+		gift:            gift,
+		discount:        discount,
+		giftService:     giftService,
+		discountService: discountService,
+		audit:           audit,
+	}
+	err := gocron.Every(1).Minute().Do(func() {
+		if err := s.failStale(); err != nil {
+			log.Error().Err(err).Msg("failed to mark stale jobs as failed")
+		}
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to start gocron for failing stale jobs")
+	}
+	return s
+}
+
+// staleAfter is how long a pending or running job can go without progress before it is considered interrupted.
+// Every batch updates the job, so only a job whose process stopped, such as on a restart, stays unchanged this long.
+const staleAfter = 10 * time.Minute
+
+// failStale marks the jobs that were interrupted before they finished as failed. They are not resumed: the codes
+// generated so far are kept, and a new job can be created for the rest. It is called by the scheduler every minute.
+func (s *Service) failStale() error {
+	n, err := s.job.FailStale(time.Now().Add(-staleAfter), "job was interrupted before it finished")
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Int64("jobs", n).Msg("marked interrupted bulk generation jobs as failed")
+	}
+	return nil
+}
+
+func (s *Service) FromDBModel(j *job.Job) *DTO {
+	var progress float64
+	if j.Count > 0 {
+		progress = float64(j.Generated) * 100 / float64(j.Count)
+	}
+	return &DTO{
+		ID:         j.ID,
+		Type:       j.CodeType,
+		Prefix:     j.Prefix,
+		Count:      j.Count,
+		Generated:  j.Generated,
+		Progress:   progress,
+		Status:     j.Status,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		UpdatedAt:  j.UpdatedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
@@ -0,0 +1,192 @@
+package redemption
+
+import (
+	"context"
+	"database/sql"
+	"discount/db"
+	"discount/internal/serr"
+	discountStorage "discount/storage/discount"
+	"discount/storage/redemption"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type DTO struct {
+	ID         int64      `json:"id"`
+	Code       string     `json:"code"`
+	CodeType   string     `json:"codeType"`
+	UserID     string     `json:"userId"`
+	OrderID    string     `json:"orderId"`
+	Amount     int64      `json:"amount"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReversedAt *time.Time `json:"reversedAt"`
+}
+
+type ListDTO struct {
+	Items    []*DTO `json:"items"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+}
+
+func (s *Service) ListByCode(code string, page, pageSize int) (*ListDTO, error) {
+	redemptions, total, err := s.redemption.GetAllByCode(code, pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.toListDTO(redemptions, total, page, pageSize), nil
+}
+
+func (s *Service) ListByUser(userID string, page, pageSize int) (*ListDTO, error) {
+	redemptions, total, err := s.redemption.GetAllByUser(userID, pageSize, (page-1)*pageSize, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.toListDTO(redemptions, total, page, pageSize), nil
+}
+
+// Reverse restores the usage consumed by a redemption, for example when its order is refunded.
+// A redemption can only be reversed once.
+func (s *Service) Reverse(id int64) (*DTO, error) {
+	r, err := s.redemption.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.reverse(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.FromDBModel(r), nil
+}
+
+// ReverseByOrder reverses every redemption of an order that has not been reversed yet. The discount redemptions are
+// reversed together in one transaction, so either all of them are reversed or none is. Gift usages live in Redis and
+// are restored one by one afterwards; if one fails, the gift redemptions reversed so far stay reversed, and since
+// only the redemptions not reversed yet are picked up, reversing the order again finishes it.
+func (s *Service) ReverseByOrder(orderID string) ([]*DTO, error) {
+	redemptions, err := s.redemption.GetActiveByOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(redemptions) == 0 {
+		return nil, serr.ValidationErr("orderId", "redemption not found", serr.ErrRedemptionNotFound)
+	}
+	var discounts, gifts []*redemption.Redemption
+	for _, r := range redemptions {
+		if r.CodeType == redemption.CodeTypeDiscount {
+			discounts = append(discounts, r)
+		} else {
+			gifts = append(gifts, r)
+		}
+	}
+	if len(discounts) > 0 {
+		err = db.Transaction(context.Background(), func(tx *sql.Tx) error {
+			for _, r := range discounts {
+				if err := s.reverseDiscount(tx, r); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range gifts {
+		err = s.reverseGift(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := make([]*DTO, 0, len(redemptions))
+	for _, r := range append(discounts, gifts...) {
+		result = append(result, s.FromDBModel(r))
+	}
+	return result, nil
+}
+
+// reverse marks the redemption as reversed and restores the usage of its code. Discount usages live in the
+// database and are restored in the same transaction. Gift usages live in Redis, so the redemption is marked first
+// to rule out a double reversal and unmarked again if the usage cannot be restored, or if the campaign cannot be
+// refunded, in which case the restored usage is taken back too.
+// The redeemed amount is given back to the budget of the campaign the code belongs to.
+func (s *Service) reverse(r *redemption.Redemption) error {
+	if r.ReversedAt != nil {
+		return serr.ValidationErr("id", "redemption has already been reversed", serr.ErrRedemptionAlreadyReversed)
+	}
+	if r.CodeType == redemption.CodeTypeDiscount {
+		return db.Transaction(context.Background(), func(tx *sql.Tx) error {
+			return s.reverseDiscount(tx, r)
+		})
+	}
+	return s.reverseGift(r)
+}
+
+// reverseDiscount reverses a discount redemption in the transaction. See reverse.
+func (s *Service) reverseDiscount(tx *sql.Tx, r *redemption.Redemption) error {
+	rs, err := s.redemption.WithTX(tx)
+	if err != nil {
+		return err
+	}
+	ds, err := s.discount.WithTX(tx)
+	if err != nil {
+		return err
+	}
+	err = rs.MarkReversed(r)
+	if err != nil {
+		return err
+	}
+	err = ds.DecreaseUsedCount(r.Code)
+	if errors.Is(err, discountStorage.ErrNoRowToUpdate) {
+		return serr.ValidationErr("code", "discount has no usage to restore", serr.ErrNoUsageToRestore)
+	}
+	if err != nil {
+		return err
+	}
+	d, err := ds.GetByCodeWithDeleted(r.Code)
+	if err != nil || d.CampaignID == nil {
+		return err
+	}
+	cs, err := s.campaign.WithTX(tx)
+	if err != nil {
+		return err
+	}
+	return cs.Refund(*d.CampaignID, r.Amount)
+}
+
+// reverseGift reverses a gift redemption. See reverse.
+func (s *Service) reverseGift(r *redemption.Redemption) error {
+	err := s.redemption.MarkReversed(r)
+	if err != nil {
+		return err
+	}
+	g, err := s.gift.DecreaseUsedCountRedis(r.Code, r.Amount)
+	if err != nil {
+		if uErr := s.redemption.UnmarkReversed(r); uErr != nil {
+			log.Error().Err(uErr).Int64("redemption", r.ID).Msg("failed to unmark reversed redemption")
+		}
+		return err
+	}
+	if g.CampaignID != nil {
+		err = s.campaign.Refund(*g.CampaignID, r.Amount)
+		if err != nil {
+			if rErr := s.gift.RevertDecreaseUsedCountRedis(r.Code, r.Amount); rErr != nil {
+				log.Error().Err(rErr).Int64("redemption", r.ID).Msg("failed to take back restored gift usage")
+			}
+			if uErr := s.redemption.UnmarkReversed(r); uErr != nil {
+				log.Error().Err(uErr).Int64("redemption", r.ID).Msg("failed to unmark reversed redemption")
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) toListDTO(redemptions []*redemption.Redemption, total, page, pageSize int) *ListDTO {
+	result := &ListDTO{Items: make([]*DTO, 0, len(redemptions)), Total: total, Page: page, PageSize: pageSize}
+	for _, r := range redemptions {
+		result.Items = append(result.Items, s.FromDBModel(r))
+	}
+	return result
+}
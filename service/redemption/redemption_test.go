@@ -0,0 +1,142 @@
+package redemption_test
+
+import (
+	"discount/db"
+	redemptionService "discount/service/redemption"
+	"discount/storage/campaign"
+	"discount/storage/discount"
+	"discount/storage/gift"
+	"discount/storage/redemption"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"testing"
+	"time"
+)
+
+type storages struct {
+	redemption redemption.Storage
+	gift       gift.Storage
+	discount   discount.Storage
+}
+
+func TestReverseDeletedDiscount(t *testing.T) {
+	service, s := setup()
+
+	d := &discount.Discount{
+		Code:           "REVERSE",
+		PercentOff:     10,
+		UsageLimit:     10,
+		ExpirationDate: time.Now().AddDate(0, 0, 10),
+		StartDateTime:  time.Now(),
+		Status:         discount.StatusActive,
+	}
+	err := s.discount.Create(d)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = s.discount.IncreaseUsedCount(d.Code, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r := &redemption.Redemption{Code: d.Code, CodeType: redemption.CodeTypeDiscount, OrderID: "order", Amount: 100}
+	err = s.redemption.Create(r)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = s.discount.DeleteByCode(d.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = service.Reverse(r.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, err := s.discount.GetByCodeWithDeleted(d.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.UsedCount != 0 {
+		t.Fatalf("expected used count 0, got %v", got.UsedCount)
+	}
+
+	_, err = s.discount.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestReverseDeletedGift(t *testing.T) {
+	service, s := setup()
+
+	g := &gift.Gift{
+		Code:           "REVERSE",
+		GiftAmount:     100,
+		UsageLimit:     10,
+		ExpirationDate: time.Now().AddDate(0, 0, 10),
+		StartDateTime:  time.Now(),
+		Status:         gift.StatusActive,
+	}
+	err := s.gift.Create(g)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = s.gift.IncreaseUsedCountRedis(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	r := &redemption.Redemption{Code: g.Code, CodeType: redemption.CodeTypeGift, OrderID: "order", Amount: 100}
+	err = s.redemption.Create(r)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	err = s.gift.DeleteByCode(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = service.Reverse(r.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, err := s.gift.Restore(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.UsedCount != 0 {
+		t.Fatalf("expected used count 0, got %v", got.UsedCount)
+	}
+
+	err = s.gift.DeleteByCode(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = s.gift.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func setup() (*redemptionService.Service, *storages) {
+	// Discount reversals run in transactions, which use the database opened by NewPostgres.
+	postgres, err := db.NewPostgres("test", "arv123", "asd123ASD", "localhost", "5432", 10, 10)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	s := &storages{
+		redemption: redemption.New(postgres),
+		gift:       gift.New(postgres, redisClient),
+		discount:   discount.New(postgres),
+	}
+	// Deleting only hides codes, so purge the ones earlier tests left behind to free their codes.
+	_, _ = s.gift.PurgeDeleted(time.Now().Add(time.Minute))
+	_, _ = s.discount.PurgeDeleted(time.Now().Add(time.Minute))
+
+	service := redemptionService.New(s.redemption, s.gift, s.discount, campaign.New(postgres))
+	return service, s
+}
@@ -0,0 +1,42 @@
+package redemption
+
+import (
+	campaignStorage "discount/storage/campaign"
+	discountStorage "discount/storage/discount"
+	giftStorage "discount/storage/gift"
+	"discount/storage/redemption"
+)
+
+type Service struct {
+	redemption redemption.Storage
+	gift       giftStorage.Storage
+	discount   discountStorage.Storage
+	campaign   campaignStorage.Storage
+}
+
+func New(
+	redemption redemption.Storage,
+	gift giftStorage.Storage,
+	discount discountStorage.Storage,
+	campaign campaignStorage.Storage,
+) *Service {
+	return &Service{
+		redemption: redemption,
+		gift:       gift,
+		discount:   discount,
+		campaign:   campaign,
+	}
+}
+
+func (s *Service) FromDBModel(r *redemption.Redemption) *DTO {
+	return &DTO{
+		ID:         r.ID,
+		Code:       r.Code,
+		CodeType:   r.CodeType,
+		UserID:     r.UserID,
+		OrderID:    r.OrderID,
+		Amount:     r.Amount,
+		CreatedAt:  r.CreatedAt,
+		ReversedAt: r.ReversedAt,
+	}
+}
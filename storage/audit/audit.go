@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"discount/db"
+	"discount/internal/serr"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const entryColumns = "id,actor,action,entity,entity_id,before,after,trace_id,created_at"
+
+// Entry records an administrative change to an entity, such as a gift or a campaign. Before and After are the
+// entity as JSON around the change; Before is empty for creations and After for deletions.
+type Entry struct {
+	ID        int64     `db:"id"`
+	Actor     string    `db:"actor"`
+	Action    string    `db:"action"`
+	Entity    string    `db:"entity"`
+	EntityID  string    `db:"entity_id"`
+	Before    Snapshot  `db:"before"`
+	After     Snapshot  `db:"after"`
+	TraceID   string    `db:"trace_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Snapshot is an entity encoded as JSON. An empty snapshot is stored as NULL.
+type Snapshot []byte
+
+// Filter narrows down the entries read by GetAll. From and To bound the time of the change, To exclusive; zero
+// fields do not filter.
+type Filter struct {
+	Actor    string
+	Action   string
+	Entity   string
+	EntityID string
+	TraceID  string
+	From     time.Time
+	To       time.Time
+}
+
+// Create inserts a new audit entry.
+func (s Storage) Create(e *Entry) error {
+	sqlStmt := `
+	INSERT INTO audit_log (actor, action, entity, entity_id, before, after, trace_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	                     RETURNING id, created_at`
+	err := s.db.QueryRow(sqlStmt, e.Actor, e.Action, e.Entity, e.EntityID, e.Before, e.After, e.TraceID).
+		Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return serr.DBError("Create", "audit", err)
+	}
+	return nil
+}
+
+// GetAll returns the entries matching the filter page by page, newest first.
+func (s Storage) GetAll(f *Filter, limit, offset int, count bool) ([]*Entry, int, error) {
+	c := f.conditions()
+	var total int
+	if count {
+		err := s.db.QueryRow("SELECT count(*) FROM audit_log"+c.Where(), c.Args()...).Scan(&total)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "audit", err)
+		}
+	}
+	args := append(c.Args(), limit, offset)
+	pagination := " LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+	order := " ORDER BY id DESC"
+	rows, err := s.db.Query("SELECT "+entryColumns+" FROM audit_log"+c.Where()+order+pagination, args...)
+	if err != nil {
+		return nil, 0, serr.DBError("List", "audit", err)
+	}
+	defer rows.Close()
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		e, err := s.scanEntry(rows)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "audit", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+func (f *Filter) conditions() *db.Conditions {
+	c := &db.Conditions{}
+	if f.Actor != "" {
+		c.Add("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		c.Add("action = ?", f.Action)
+	}
+	if f.Entity != "" {
+		c.Add("entity = ?", f.Entity)
+	}
+	if f.EntityID != "" {
+		c.Add("entity_id = ?", f.EntityID)
+	}
+	if f.TraceID != "" {
+		c.Add("trace_id = ?", f.TraceID)
+	}
+	if !f.From.IsZero() {
+		c.Add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		c.Add("created_at < ?", f.To)
+	}
+	return c
+}
+
+func (s Storage) scanEntry(scanner db.Scanner) (*Entry, error) {
+	e := &Entry{}
+	err := scanner.Scan(&e.ID, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &e.Before, &e.After, &e.TraceID,
+		&e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s Snapshot) Value() (driver.Value, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	return []byte(s), nil
+}
+
+func (s *Snapshot) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = nil
+	case []byte:
+		*s = bytes.Clone(v)
+	default:
+		return errors.New("snapshot must be scanned from JSON")
+	}
+	return nil
+}
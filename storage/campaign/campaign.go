@@ -0,0 +1,172 @@
+package campaign
+
+import (
+	"database/sql"
+	"discount/db"
+	"discount/internal/serr"
+	"errors"
+	"time"
+)
+
+const campaignColumns = "id,name,start_date_time,expiration_date,budget,spent,created_at,updated_at"
+
+// Campaign groups gifts and discounts of one promotion. The codes of a campaign can only be used within its
+// date window, and together they can take at most Budget off orders. A zero Budget means no budget limit.
+type Campaign struct {
+	ID             int64     `db:"id"`
+	Name           string    `db:"name"`
+	StartDateTime  time.Time `db:"start_date_time"`
+	ExpirationDate time.Time `db:"expiration_date"`
+	Budget         int64     `db:"budget"`
+	Spent          int64     `db:"spent"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// Validate reports whether the campaign can pay amount at the given time. Like gifts and discounts, a zero
+// StartDateTime or ExpirationDate leaves that side of the date window open. A campaign that has reached its
+// budget cannot pay anything, not even a zero amount.
+func (c *Campaign) Validate(now time.Time, amount int64) error {
+	if !c.StartDateTime.IsZero() && now.Before(c.StartDateTime) {
+		return serr.ValidationErr("code", "campaign has not started yet", serr.ErrCampaignNotStarted)
+	}
+	if !c.ExpirationDate.IsZero() && !now.Before(c.ExpirationDate) {
+		return serr.ValidationErr("code", "campaign has ended", serr.ErrCampaignEnded)
+	}
+	if c.Budget > 0 && (c.Spent >= c.Budget || c.Spent+amount > c.Budget) {
+		return serr.ValidationErr("code", "campaign budget is used up", serr.ErrCampaignBudgetUsedUp)
+	}
+	return nil
+}
+
+func (s Storage) Create(c *Campaign) error {
+	sqlStmt := `
+	INSERT INTO campaign (name, start_date_time, expiration_date, budget, spent)
+	VALUES ($1, $2, $3, $4, $5)
+	                     RETURNING id, created_at, updated_at`
+	err := s.db.QueryRow(sqlStmt, c.Name, c.StartDateTime, c.ExpirationDate, c.Budget, c.Spent).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update changes the name, date window and budget of a campaign. Spent is only changed through Spend and Refund.
+func (s Storage) Update(c *Campaign) error {
+	sqlStmt := `
+	UPDATE campaign SET name = $1, start_date_time = $2, expiration_date = $3, budget = $4, updated_at = now()
+	WHERE id = $5 RETURNING spent, created_at, updated_at`
+	err := s.db.QueryRow(sqlStmt, c.Name, c.StartDateTime, c.ExpirationDate, c.Budget, c.ID).
+		Scan(&c.Spent, &c.CreatedAt, &c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errCampaignNotFound()
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s Storage) GetByID(id int64) (*Campaign, error) {
+	sqlStmt := "SELECT " + campaignColumns + " FROM campaign WHERE id = $1"
+	c, err := s.scanCampaign(s.db.QueryRow(sqlStmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errCampaignNotFound()
+	}
+	if err != nil {
+		return nil, serr.DBError("GetByID", "campaign", err)
+	}
+	return c, nil
+}
+
+// Delete removes a campaign. Its gifts and discounts are kept and no longer belong to any campaign.
+func (s Storage) Delete(id int64) error {
+	row, err := s.db.Exec("DELETE FROM campaign WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err != nil || count == 0 {
+		return errCampaignNotFound()
+	}
+	return nil
+}
+
+// Spend charges amount to the budget of a campaign after checking its date window. The budget is checked in
+// the same statement as the charge, so concurrent redemptions can never push spent past budget.
+func (s Storage) Spend(id, amount int64, now time.Time) error {
+	c, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	err = c.Validate(now, amount)
+	if err != nil {
+		return err
+	}
+	sqlStmt := `
+	UPDATE campaign SET spent = spent + $2, updated_at = now()
+	WHERE id = $1 AND (budget = 0 OR spent + $2 <= budget)`
+	row, err := s.db.Exec(sqlStmt, id, amount)
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err != nil || count == 0 {
+		return serr.ValidationErr("code", "campaign budget is used up", serr.ErrCampaignBudgetUsedUp)
+	}
+	return nil
+}
+
+// Refund gives amount back to the budget of a campaign, for example when a redemption is reversed.
+func (s Storage) Refund(id, amount int64) error {
+	sqlStmt := `
+	UPDATE campaign SET spent = GREATEST(spent - $2, 0), updated_at = now()
+	WHERE id = $1`
+	row, err := s.db.Exec(sqlStmt, id, amount)
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err != nil || count == 0 {
+		return ErrNoRowToUpdate
+	}
+	return nil
+}
+
+func (s Storage) GetAllByPage(limit, offset int, count bool) ([]*Campaign, int, error) {
+	var total int
+	if count {
+		err := s.db.QueryRow("SELECT count(*) FROM campaign").Scan(&total)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "campaign", err)
+		}
+	}
+	pagination := " LIMIT $1 OFFSET $2"
+	order := " ORDER BY created_at DESC"
+	rows, err := s.db.Query("SELECT "+campaignColumns+" FROM campaign"+order+pagination, limit, offset)
+	if err != nil {
+		return nil, 0, serr.DBError("List", "campaign", err)
+	}
+	defer rows.Close()
+	campaigns := make([]*Campaign, 0)
+	for rows.Next() {
+		c, err := s.scanCampaign(rows)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "campaign", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+	return campaigns, total, nil
+}
+
+func (s Storage) scanCampaign(scanner db.Scanner) (*Campaign, error) {
+	c := &Campaign{}
+	err := scanner.Scan(&c.ID, &c.Name, &c.StartDateTime, &c.ExpirationDate, &c.Budget, &c.Spent, &c.CreatedAt,
+		&c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func errCampaignNotFound() error {
+	return serr.ValidationErr("id", "campaign not found", serr.ErrCampaignNotFound)
+}
@@ -0,0 +1,111 @@
+package discount
+
+import (
+	"database/sql/driver"
+	"discount/internal/serr"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// Condition types. A category or sku condition looks at the items of the cart, a segment condition at the
+// segments of the customer and a channel condition at the sales channel of the order.
+const (
+	ConditionCategory = "category"
+	ConditionSKU      = "sku"
+	ConditionSegment  = "segment"
+	ConditionChannel  = "channel"
+)
+
+// Condition operators. With in, the condition is met when the cart has at least one of the values; with not_in,
+// when it has none of them.
+const (
+	OperatorIn    = "in"
+	OperatorNotIn = "not_in"
+)
+
+// Condition restricts a discount to carts with, or without, some of the given values.
+type Condition struct {
+	Type     string   `json:"type"`
+	Operator string   `json:"operator"`
+	Values   []string `json:"values"`
+}
+
+// Conditions are stored as a JSON array. A discount can only be used when all of its conditions are met.
+type Conditions []Condition
+
+// Cart is the context a discount is used in.
+type Cart struct {
+	Channel  string     `json:"channel"`
+	Segments []string   `json:"segments"`
+	Items    []CartItem `json:"items"`
+}
+
+type CartItem struct {
+	SKU      string `json:"sku"`
+	Category string `json:"category"`
+}
+
+// Validate checks that every condition has a known type and operator and at least one value.
+func (c Conditions) Validate() error {
+	for _, cond := range c {
+		switch cond.Type {
+		case ConditionCategory, ConditionSKU, ConditionSegment, ConditionChannel:
+		default:
+			return serr.ValidationErr("conditions", "invalid discount condition", serr.ErrInvalidCondition)
+		}
+		if (cond.Operator != OperatorIn && cond.Operator != OperatorNotIn) || len(cond.Values) == 0 {
+			return serr.ValidationErr("conditions", "invalid discount condition", serr.ErrInvalidCondition)
+		}
+	}
+	return nil
+}
+
+// Failed returns the conditions the cart does not meet.
+func (c Conditions) Failed(cart *Cart) Conditions {
+	var failed Conditions
+	for _, cond := range c {
+		if !cond.met(cart) {
+			failed = append(failed, cond)
+		}
+	}
+	return failed
+}
+
+func (c Condition) met(cart *Cart) bool {
+	var values []string
+	switch c.Type {
+	case ConditionCategory:
+		for _, item := range cart.Items {
+			values = append(values, item.Category)
+		}
+	case ConditionSKU:
+		for _, item := range cart.Items {
+			values = append(values, item.SKU)
+		}
+	case ConditionSegment:
+		values = cart.Segments
+	case ConditionChannel:
+		values = []string{cart.Channel}
+	}
+	found := slices.ContainsFunc(values, func(v string) bool { return v != "" && slices.Contains(c.Values, v) })
+	if c.Operator == OperatorNotIn {
+		return !found
+	}
+	return found
+}
+
+func (c Conditions) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(c)
+}
+
+func (c *Conditions) Scan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("conditions must be scanned from JSON")
+	}
+	return json.Unmarshal(b, c)
+}
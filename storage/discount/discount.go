@@ -1,13 +1,19 @@
 package discount
 
 import (
+	"database/sql"
+	"discount/db"
+	"discount/internal/schedule"
 	"discount/internal/serr"
+	"errors"
+	"github.com/lib/pq"
 	"time"
 )
 
 const discountColumns = "id" +
 	",code,percent_off,discount_amount,usage_limit,used_count,expiration_date,start_date_time,max_amount" +
-	",min_amount,created_at,updated_at"
+	",min_amount,exclusive,stackable_with_gift,stackable_with_discount,campaign_id,conditions,schedule,status" +
+	",job_id,created_at,updated_at,version"
 
 type Discount struct {
 	ID             int64     `db:"id"`
@@ -20,24 +26,230 @@ type Discount struct {
 	StartDateTime  time.Time `db:"start_date_time"`
 	MaxAmount      int64     `db:"max_amount"`
 	MinAmount      int64     `db:"min_amount"`
-	CreatedAt      time.Time `db:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at"`
+	// Exclusive discounts cannot be combined with any other code. Otherwise StackableWithGift and
+	// StackableWithDiscount tell which kinds of codes the discount can be combined with.
+	Exclusive             bool   `db:"exclusive"`
+	StackableWithGift     bool   `db:"stackable_with_gift"`
+	StackableWithDiscount bool   `db:"stackable_with_discount"`
+	CampaignID            *int64 `db:"campaign_id"`
+	// Conditions restrict the discount to carts that meet all of them.
+	Conditions Conditions `db:"conditions"`
+	// Schedule restricts the discount to recurring time windows within its validity.
+	Schedule schedule.Schedule `db:"schedule"`
+	// Status is the lifecycle status of the discount. It is only changed through SetStatus.
+	Status string `db:"status"`
+	// JobID is the bulk generation job that created the discount, if any.
+	JobID     *int64    `db:"job_id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+	// Version is raised by every Update and status change, so an update can be made conditional on the version
+	// it was based on. Redemptions do not change it.
+	Version int64 `db:"version"`
+}
+
+// Statuses a discount can be filtered by, next to its lifecycle statuses. They follow Validate: an active discount
+// is scheduled before it starts, expired from its expiration date on and exhausted once it is used up.
+const (
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusExpired   = "expired"
+	StatusExhausted = "exhausted"
+)
+
+// exhaustedCondition matches the discounts that are used up.
+const exhaustedCondition = "(usage_limit > 0 AND used_count >= usage_limit)"
+
+// Filter narrows down the discounts read by ForEach. From and To bound the creation time, To exclusive; zero fields
+// do not filter.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Prefix string
+	Status string
+}
+
+// Validate reports whether the discount can be used at the given time. A zero StartDateTime or ExpirationDate
+// leaves that side of the validity window open, and a zero UsageLimit means the discount can be used without limit.
+func (d *Discount) Validate(now time.Time) error {
+	switch d.Status {
+	case StatusPaused:
+		return serr.ValidationErr("code", "discount code is paused", serr.ErrDiscountPaused)
+	case StatusDraft, StatusArchived:
+		return serr.ValidationErr("code", "discount code is not available", serr.ErrDiscountUnavailable)
+	}
+	if !d.StartDateTime.IsZero() && now.Before(d.StartDateTime) {
+		return serr.ValidationErr("code", "discount code is not active yet", serr.ErrDiscountNotStarted)
+	}
+	if !d.ExpirationDate.IsZero() && !now.Before(d.ExpirationDate) {
+		return serr.ValidationErr("code", "discount code has expired", serr.ErrDiscountExpired)
+	}
+	if !d.Schedule.Allows(now) {
+		return serr.ValidationErr("code", "discount code cannot be used at this time", serr.ErrDiscountOutOfSchedule)
+	}
+	if d.UsageLimit > 0 && d.UsedCount >= d.UsageLimit {
+		return serr.ValidationErr("code", "discount usage limit reached", serr.ErrDiscountUsageLimitReached)
+	}
+	return nil
+}
+
+// StatusAt tells whether the discount is scheduled, active, expired or exhausted at the given time, or the
+// lifecycle status of a discount that is not active.
+func (d *Discount) StatusAt(now time.Time) string {
+	switch {
+	case d.Status != "" && d.Status != StatusActive:
+		return d.Status
+	case !d.StartDateTime.IsZero() && now.Before(d.StartDateTime):
+		return StatusScheduled
+	case !d.ExpirationDate.IsZero() && !now.Before(d.ExpirationDate):
+		return StatusExpired
+	case d.UsageLimit > 0 && d.UsedCount >= d.UsageLimit:
+		return StatusExhausted
+	}
+	return StatusActive
 }
 
 func (s Storage) Create(d *Discount) error {
 	sqlStmt := `
 	INSERT INTO discount (code, percent_off, discount_amount, usage_limit, used_count, expiration_date, start_date_time,
-	                      max_amount, min_amount)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
-	                     RETURNING id, code, created_at, updated_at`
-	err := s.db.QueryRow(sqlStmt, d.Code, d.PercentOff, d.DiscountAmount, d.UsageLimit, d.UsedCount, d.ExpirationDate,
-		d.StartDateTime, d.MaxAmount, d.MinAmount).Scan(&d.ID, &d.Code, &d.CreatedAt, &d.UpdatedAt)
+	                      max_amount, min_amount, exclusive, stackable_with_gift, stackable_with_discount, campaign_id,
+	                      conditions, schedule, status, job_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) 
+	                     RETURNING id, code, created_at, updated_at, version`
+	err := s.db.QueryRow(sqlStmt, d.insertArgs()...).Scan(&d.ID, &d.Code, &d.CreatedAt, &d.UpdatedAt, &d.Version)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertBatch inserts the discounts in one statement and returns how many were inserted. Discounts whose code is
+// already taken are skipped instead of failing the whole batch.
+func (s Storage) InsertBatch(discounts []*Discount) (int64, error) {
+	if len(discounts) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(discounts)*discountInsertColumns)
+	for _, d := range discounts {
+		args = append(args, d.insertArgs()...)
+	}
+	sqlStmt := `
+	INSERT INTO discount (code, percent_off, discount_amount, usage_limit, used_count, expiration_date, start_date_time,
+	                      max_amount, min_amount, exclusive, stackable_with_gift, stackable_with_discount, campaign_id,
+	                      conditions, schedule, status, job_id)
+	VALUES ` + db.ValuesPlaceholders(len(discounts), discountInsertColumns) + `
+	ON CONFLICT (code) DO NOTHING`
+	row, err := s.db.Exec(sqlStmt, args...)
+	if err != nil {
+		return 0, serr.DBError("InsertBatch", "discount", err)
+	}
+	return row.RowsAffected()
+}
+
+// ForEachByJob calls fn for every discount created by the bulk generation job, in the order they were inserted.
+// The discounts are read from a cursor one by one, so the job may be arbitrarily large.
+func (s Storage) ForEachByJob(jobID int64, fn func(d *Discount) error) error {
+	sqlStmt := "SELECT " + discountColumns + " FROM discount WHERE job_id = $1 AND deleted_at IS NULL ORDER BY id"
+	rows, err := s.db.Query(sqlStmt, jobID)
+	if err != nil {
+		return serr.DBError("ForEachByJob", "discount", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d, err := s.scanDiscount(rows)
+		if err != nil {
+			return serr.DBError("ForEachByJob", "discount", err)
+		}
+		if err = fn(d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ForEach calls fn for every discount matching the filter, in the order they were created. The discounts are read
+// from a cursor one by one, so any number of them can be gone through.
+func (s Storage) ForEach(f *Filter, now time.Time, fn func(d *Discount) error) error {
+	c, err := f.conditions(now)
+	if err != nil {
+		return err
+	}
+	rows, err := s.db.Query("SELECT "+discountColumns+" FROM discount"+c.Where()+" ORDER BY id", c.Args()...)
+	if err != nil {
+		return serr.DBError("ForEach", "discount", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		d, err := s.scanDiscount(rows)
+		if err != nil {
+			return serr.DBError("ForEach", "discount", err)
+		}
+		if err = fn(d); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (f *Filter) conditions(now time.Time) (*db.Conditions, error) {
+	c := &db.Conditions{}
+	c.Add("deleted_at IS NULL")
+	if !f.From.IsZero() {
+		c.Add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		c.Add("created_at < ?", f.To)
+	}
+	if f.Prefix != "" {
+		c.Add("code LIKE ?", db.LikePrefix(f.Prefix))
+	}
+	switch f.Status {
+	case "":
+	case StatusDraft, StatusPaused, StatusArchived:
+		c.Add("status = ?", f.Status)
+	case StatusScheduled:
+		c.Add("status = ? AND start_date_time > ?", StatusActive, now)
+	case StatusExpired:
+		c.Add("status = ? AND expiration_date > ? AND expiration_date <= ?", StatusActive, time.Time{}, now)
+	case StatusExhausted:
+		c.Add("status = ? AND "+exhaustedCondition, StatusActive)
+	case StatusActive:
+		c.Add("status = ? AND start_date_time <= ? AND (expiration_date <= ? OR expiration_date > ?) AND NOT "+
+			exhaustedCondition, StatusActive, now, time.Time{}, now)
+	default:
+		return nil, serr.ValidationErr("status", "invalid status", serr.ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// ExistingCodes returns which of the codes are already taken by a discount. Deleted discounts keep their codes
+// until they are purged.
+func (s Storage) ExistingCodes(codes []string) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT code FROM discount WHERE code = ANY($1)", pq.Array(codes))
+	if err != nil {
+		return nil, serr.DBError("ExistingCodes", "discount", err)
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var code string
+		if err = rows.Scan(&code); err != nil {
+			return nil, serr.DBError("ExistingCodes", "discount", err)
+		}
+		existing[code] = true
+	}
+	return existing, rows.Err()
+}
+
+// CountLike returns how many discounts have a code matching the SQL LIKE expression.
+func (s Storage) CountLike(like string) (int64, error) {
+	var count int64
+	err := s.db.QueryRow("SELECT count(*) FROM discount WHERE code LIKE $1", like).Scan(&count)
+	if err != nil {
+		return 0, serr.DBError("CountLike", "discount", err)
+	}
+	return count, nil
+}
+
 func (s Storage) CreateBulk(discounts []*Discount) error {
 	for _, discount := range discounts {
 		err := s.Create(discount)
@@ -48,43 +260,69 @@ func (s Storage) CreateBulk(discounts []*Discount) error {
 	return nil
 }
 
+// Update writes the discount to the database if it is still at d.Version, and raises its version. The used count is
+// left as it is, since it is kept by redemptions rather than by updates. A discount that has been updated since d
+// was read fails with a version mismatch.
 func (s Storage) Update(d *Discount) error {
 	sqlStmt := `
-	UPDATE discount SET code = $1, percent_off = $2, discount_amount = $3, usage_limit = $4, used_count = $5, 
-	                   expiration_date = $6, start_date_time = $7, max_amount = $8, min_amount = $9, updated_at = now()
-	WHERE id = $10 RETURNING updated_at`
-	err := s.db.QueryRow(sqlStmt, d.Code, d.PercentOff, d.DiscountAmount, d.UsageLimit, d.UsedCount, d.ExpirationDate,
-		d.StartDateTime, d.MaxAmount, d.MinAmount, d.ID).Scan(&d.UpdatedAt)
+	UPDATE discount SET code = $1, percent_off = $2, discount_amount = $3, usage_limit = $4, expiration_date = $5,
+	                   start_date_time = $6, max_amount = $7, min_amount = $8, exclusive = $9,
+	                   stackable_with_gift = $10, stackable_with_discount = $11, campaign_id = $12, conditions = $13,
+	                   schedule = $14, version = version + 1, updated_at = now()
+	WHERE id = $15 AND version = $16 AND deleted_at IS NULL RETURNING used_count, version, updated_at`
+	err := s.db.QueryRow(sqlStmt, d.Code, d.PercentOff, d.DiscountAmount, d.UsageLimit, d.ExpirationDate,
+		d.StartDateTime, d.MaxAmount, d.MinAmount, d.Exclusive, d.StackableWithGift, d.StackableWithDiscount,
+		d.CampaignID, d.Conditions, d.Schedule, d.ID, d.Version).Scan(&d.UsedCount, &d.Version, &d.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err = s.GetByID(d.ID); err != nil {
+			return err
+		}
+		return serr.PreconditionFailedErr("version", "version mismatch", serr.ErrVersionMismatch)
+	}
 	if err != nil {
-		return err
+		return serr.DBError("Update", "discount", err)
 	}
 	return nil
 }
 
 func (s Storage) GetByCode(code string) (*Discount, error) {
+	sqlStmt := "SELECT " + discountColumns + " FROM discount WHERE code = $1 AND deleted_at IS NULL"
+	d, err := s.scanDiscount(s.db.QueryRow(sqlStmt, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("code", "invalid discount code", serr.ErrInvalidDiscountCode)
+	}
+	if err != nil {
+		return nil, serr.DBError("GetByCode", "discount", err)
+	}
+	return d, nil
+}
+
+// GetByCodeWithDeleted returns the discount with the code even if it has been deleted, for the records kept about
+// it, such as its redemptions.
+func (s Storage) GetByCodeWithDeleted(code string) (*Discount, error) {
 	sqlStmt := "SELECT " + discountColumns + " FROM discount WHERE code = $1"
-	d := &Discount{}
-	err := s.db.QueryRow(sqlStmt, code).Scan(&d.ID, &d.Code, &d.PercentOff, &d.DiscountAmount, &d.UsageLimit,
-		&d.UsedCount, &d.ExpirationDate, &d.StartDateTime, &d.MaxAmount, &d.MinAmount, &d.CreatedAt, &d.UpdatedAt)
+	d, err := s.scanDiscount(s.db.QueryRow(sqlStmt, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("code", "invalid discount code", serr.ErrInvalidDiscountCode)
+	}
 	if err != nil {
-		return nil, serr.ValidationErr("code", "gift", serr.ErrInvalidDiscountCode)
+		return nil, serr.DBError("GetByCodeWithDeleted", "discount", err)
 	}
 	return d, nil
 }
 
 func (s Storage) GetByID(id int64) (*Discount, error) {
-	sqlStmt := "SELECT " + discountColumns + " FROM discount WHERE id = $1"
-	d := &Discount{}
-	err := s.db.QueryRow(sqlStmt, id).Scan(&d.ID, &d.Code, &d.PercentOff, &d.DiscountAmount, &d.UsageLimit,
-		&d.UsedCount, &d.ExpirationDate, &d.StartDateTime, &d.MaxAmount, &d.MinAmount, &d.CreatedAt, &d.UpdatedAt)
+	sqlStmt := "SELECT " + discountColumns + " FROM discount WHERE id = $1 AND deleted_at IS NULL"
+	d, err := s.scanDiscount(s.db.QueryRow(sqlStmt, id))
 	if err != nil {
 		return nil, serr.ValidationErr("code", "gift", serr.ErrInvalidDiscountID)
 	}
 	return d, nil
 }
 
+// Delete marks the discount as deleted. See DeleteByCode.
 func (s Storage) Delete(id int64) error {
-	sqlStmt := "DELETE FROM discount WHERE id = $1"
+	sqlStmt := "UPDATE discount SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL"
 	row, err := s.db.Exec(sqlStmt, id)
 	if err != nil {
 		return err
@@ -95,26 +333,106 @@ func (s Storage) Delete(id int64) error {
 	return nil
 }
 
+// DeleteByCode marks the discount as deleted. Deleted discounts are left out of every read and can be restored
+// until PurgeDeleted removes them for good.
+func (s Storage) DeleteByCode(code string) error {
+	sqlStmt := "UPDATE discount SET deleted_at = now() WHERE code = $1 AND deleted_at IS NULL"
+	row, err := s.db.Exec(sqlStmt, code)
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err != nil || count == 0 {
+		return ErrNoRowToUpdate
+	}
+	return nil
+}
+
+// Restore brings back a deleted discount.
+func (s Storage) Restore(code string) (*Discount, error) {
+	sqlStmt := `
+	UPDATE discount SET deleted_at = NULL, updated_at = now()
+	WHERE code = $1 AND deleted_at IS NOT NULL RETURNING ` + discountColumns
+	d, err := s.scanDiscount(s.db.QueryRow(sqlStmt, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("code", "deleted discount not found", serr.ErrDeletedCodeNotFound)
+	}
+	if err != nil {
+		return nil, serr.DBError("Restore", "discount", err)
+	}
+	return d, nil
+}
+
+// PurgeDeleted removes the discounts deleted before t from the database for good and returns how many were
+// removed.
+func (s Storage) PurgeDeleted(t time.Time) (int64, error) {
+	row, err := s.db.Exec("DELETE FROM discount WHERE deleted_at < $1", t)
+	if err != nil {
+		return 0, serr.DBError("PurgeDeleted", "discount", err)
+	}
+	return row.RowsAffected()
+}
+
+// IncreaseUsedCount atomically increases the used count of a discount that can be used at now. The status, the
+// validity window and the usage limit are checked in the same statement, so concurrent redemptions can never push
+// used_count past usage_limit, and a discount paused after it was read is not used. When no row is updated, the
+// discount is read again and the error of Validate explains why; ErrNoRowToUpdate is returned if it does not.
+func (s Storage) IncreaseUsedCount(code string, now time.Time) error {
+	sqlStmt := `
+	UPDATE discount SET used_count = used_count + 1, updated_at = now()
+	WHERE code = $1 AND deleted_at IS NULL AND status = $2 AND start_date_time <= $3
+	  AND (expiration_date <= $4 OR expiration_date > $3) AND (usage_limit = 0 OR used_count < usage_limit)`
+	row, err := s.db.Exec(sqlStmt, code, StatusActive, now, time.Time{})
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err == nil && count > 0 {
+		return nil
+	}
+	d, err := s.GetByCode(code)
+	if err != nil {
+		return err
+	}
+	if err = d.Validate(now); err != nil {
+		return err
+	}
+	return ErrNoRowToUpdate
+}
+
+// DecreaseUsedCount restores one usage of a discount. ErrNoRowToUpdate is returned when the code does not exist
+// or has no usage to restore.
+func (s Storage) DecreaseUsedCount(code string) error {
+	sqlStmt := `
+	UPDATE discount SET used_count = used_count - 1, updated_at = now()
+	WHERE code = $1 AND used_count > 0`
+	row, err := s.db.Exec(sqlStmt, code)
+	if err != nil {
+		return err
+	}
+	if count, err := row.RowsAffected(); err != nil || count == 0 {
+		return ErrNoRowToUpdate
+	}
+	return nil
+}
+
 func (s Storage) GetAllByPage(limit, offset int, count bool) ([]*Discount, int, error) {
 	var total int
 	if count {
-		err := s.db.QueryRow("SELECT count(*) FROM discount").Scan(&total)
+		err := s.db.QueryRow("SELECT count(*) FROM discount WHERE deleted_at IS NULL").Scan(&total)
 		if err != nil {
 			return nil, 0, err
 		}
 	}
 	pagination := " LIMIT $1 OFFSET $2"
 	order := " ORDER BY created_at DESC"
-	rows, err := s.db.Query("SELECT "+discountColumns+" FROM discount"+order+pagination, limit, offset)
+	where := " WHERE deleted_at IS NULL"
+	rows, err := s.db.Query("SELECT "+discountColumns+" FROM discount"+where+order+pagination, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer rows.Close()
 	discounts := make([]*Discount, 0)
 	for rows.Next() {
-		d := &Discount{}
-		err := rows.Scan(&d.ID, &d.Code, &d.PercentOff, &d.DiscountAmount, &d.UsageLimit, &d.UsedCount, &d.ExpirationDate,
-			&d.StartDateTime, &d.MaxAmount, &d.MinAmount, &d.CreatedAt, &d.UpdatedAt)
+		d, err := s.scanDiscount(rows)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -122,3 +440,24 @@ func (s Storage) GetAllByPage(limit, offset int, count bool) ([]*Discount, int,
 	}
 	return discounts, total, nil
 }
+
+func (s Storage) scanDiscount(scanner db.Scanner) (*Discount, error) {
+	d := &Discount{}
+	err := scanner.Scan(&d.ID, &d.Code, &d.PercentOff, &d.DiscountAmount, &d.UsageLimit, &d.UsedCount,
+		&d.ExpirationDate, &d.StartDateTime, &d.MaxAmount, &d.MinAmount, &d.Exclusive,
+		&d.StackableWithGift, &d.StackableWithDiscount, &d.CampaignID, &d.Conditions, &d.Schedule, &d.Status,
+		&d.JobID, &d.CreatedAt, &d.UpdatedAt, &d.Version)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// discountInsertColumns is the number of values insertArgs returns for each discount.
+const discountInsertColumns = 17
+
+func (d *Discount) insertArgs() []any {
+	return []any{d.Code, d.PercentOff, d.DiscountAmount, d.UsageLimit, d.UsedCount, d.ExpirationDate,
+		d.StartDateTime, d.MaxAmount, d.MinAmount, d.Exclusive, d.StackableWithGift, d.StackableWithDiscount,
+		d.CampaignID, d.Conditions, d.Schedule, d.Status, d.JobID}
+}
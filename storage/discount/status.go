@@ -0,0 +1,46 @@
+package discount
+
+import (
+	"database/sql"
+	"discount/internal/serr"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// Lifecycle statuses of a discount, next to StatusActive. A discount is created as a draft or active, can be
+// paused and resumed while it is active, and is archived once it must never be used again. Only active discounts
+// can be used.
+const (
+	StatusDraft    = "draft"
+	StatusPaused   = "paused"
+	StatusArchived = "archived"
+)
+
+// transitions lists, for each status, the statuses a discount can be moved to it from. Archived discounts stay
+// archived.
+var transitions = map[string][]string{
+	StatusActive:   {StatusDraft, StatusPaused},
+	StatusPaused:   {StatusActive},
+	StatusArchived: {StatusDraft, StatusActive, StatusPaused},
+}
+
+// SetStatus moves the discount to the given status if its current status allows it; a discount that already has
+// the status is left as is. Changing the status raises the version of the discount.
+func (s Storage) SetStatus(code, status string) (*Discount, error) {
+	sqlStmt := `
+	UPDATE discount SET status = $1, version = version + CASE WHEN status = $1 THEN 0 ELSE 1 END,
+	                    updated_at = now()
+	WHERE code = $2 AND deleted_at IS NULL AND (status = $1 OR status = ANY($3)) RETURNING ` + discountColumns
+	d, err := s.scanDiscount(s.db.QueryRow(sqlStmt, status, code, pq.Array(transitions[status])))
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return nil, serr.DBError("SetStatus", "discount", err)
+		}
+		return d, nil
+	}
+	_, err = s.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	return nil, serr.ConflictErr("status", "invalid status transition", serr.ErrInvalidStatusTransition)
+}
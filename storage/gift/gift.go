@@ -2,16 +2,23 @@ package gift
 
 import (
 	"context"
+	"database/sql"
 	"discount/db"
+	"discount/internal/schedule"
 	"discount/internal/serr"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/rs/zerolog/log"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"strings"
 	"time"
 )
 
 const giftColumns = "id" +
-	",code,gift_amount,usage_limit,used_count,expiration_date,start_date_time,created_at,updated_at"
+	",code,gift_amount,usage_limit,used_count,expiration_date,start_date_time,balance_mode,remaining_balance" +
+	",exclusive,stackable_with_gift,stackable_with_discount,campaign_id,schedule,status,job_id,created_at,updated_at" +
+	",version"
 
 const giftPrefix = "GIFT:%s"
 const giftPrefixUpdate = "UPDATED_GIFT:%s"
@@ -24,24 +31,273 @@ type Gift struct {
 	UsedCount      int64     `db:"used_count"`
 	ExpirationDate time.Time `db:"expiration_date"`
 	StartDateTime  time.Time `db:"start_date_time"`
-	CreatedAt      time.Time `db:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at"`
+	// BalanceMode gifts are stored-value cards: each use debits an amount from RemainingBalance
+	// instead of taking the whole GiftAmount.
+	BalanceMode      bool  `db:"balance_mode"`
+	RemainingBalance int64 `db:"remaining_balance"`
+	// Exclusive gifts cannot be combined with any other code. Otherwise StackableWithGift and
+	// StackableWithDiscount tell which kinds of codes the gift can be combined with.
+	Exclusive             bool   `db:"exclusive"`
+	StackableWithGift     bool   `db:"stackable_with_gift"`
+	StackableWithDiscount bool   `db:"stackable_with_discount"`
+	CampaignID            *int64 `db:"campaign_id"`
+	// Schedule restricts the gift to recurring time windows within its validity.
+	Schedule schedule.Schedule `db:"schedule"`
+	// Status is the lifecycle status of the gift. It is only changed through SetStatus.
+	Status string `db:"status"`
+	// JobID is the bulk generation job that created the gift, if any.
+	JobID     *int64    `db:"job_id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+	// Version is raised by every Update and status change, so an update can be made conditional on the version
+	// it was based on. Uses do not change it.
+	Version int64 `db:"version"`
+}
+
+// Statuses a gift can be filtered by, next to its lifecycle statuses. They follow Validate: an active gift is
+// scheduled before it starts, expired from its expiration date on and exhausted once it is used up.
+const (
+	StatusScheduled = "scheduled"
+	StatusActive    = "active"
+	StatusExpired   = "expired"
+	StatusExhausted = "exhausted"
+)
+
+// exhaustedCondition matches the gifts that are used up.
+const exhaustedCondition = "((usage_limit > 0 AND used_count >= usage_limit)" +
+	" OR (balance_mode AND remaining_balance <= 0))"
+
+// Filter narrows down the gifts read by ForEach and GetAllByPage. From and To bound the creation time and
+// ExpiresFrom and ExpiresTo the expiration date, the upper bounds exclusive. MinAmount and MaxAmount bound the gift
+// amount, both inclusive. Zero fields do not filter.
+type Filter struct {
+	From        time.Time
+	To          time.Time
+	Prefix      string
+	Status      string
+	MinAmount   int64
+	MaxAmount   int64
+	ExpiresFrom time.Time
+	ExpiresTo   time.Time
+	CampaignID  int64
+}
+
+// Order sorts gifts by one of the fields in sortColumns, in descending order when Desc is set.
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// sortColumns maps the fields gifts can be sorted by to their columns.
+var sortColumns = map[string]string{
+	"code":           "code",
+	"giftAmount":     "gift_amount",
+	"usedCount":      "used_count",
+	"startDateTime":  "start_date_time",
+	"expirationDate": "expiration_date",
+	"createdAt":      "created_at",
+}
+
+// Validate reports whether the gift can be used at the given time. A zero StartDateTime or ExpirationDate
+// leaves that side of the validity window open, and a zero UsageLimit means the gift can be used without limit.
+func (g *Gift) Validate(now time.Time) error {
+	switch g.Status {
+	case StatusPaused:
+		return serr.ValidationErr("code", "gift code is paused", serr.ErrGiftPaused)
+	case StatusDraft, StatusArchived:
+		return serr.ValidationErr("code", "gift code is not available", serr.ErrGiftUnavailable)
+	}
+	if !g.StartDateTime.IsZero() && now.Before(g.StartDateTime) {
+		return serr.ValidationErr("code", "gift code is not active yet", serr.ErrGiftNotStarted)
+	}
+	if !g.ExpirationDate.IsZero() && !now.Before(g.ExpirationDate) {
+		return serr.ValidationErr("code", "gift code has expired", serr.ErrGiftExpired)
+	}
+	if !g.Schedule.Allows(now) {
+		return serr.ValidationErr("code", "gift code cannot be used at this time", serr.ErrGiftOutOfSchedule)
+	}
+	if g.UsageLimit > 0 && g.UsedCount >= g.UsageLimit {
+		return serr.ValidationErr("code", "gift usage limit reached", serr.ErrGiftUsageLimitReached)
+	}
+	if g.BalanceMode && g.RemainingBalance <= 0 {
+		return serr.ValidationErr("code", "gift balance is used up", serr.ErrGiftBalanceUsedUp)
+	}
+	return nil
+}
+
+// StatusAt tells whether the gift is scheduled, active, expired or exhausted at the given time, or the lifecycle
+// status of a gift that is not active.
+func (g *Gift) StatusAt(now time.Time) string {
+	switch {
+	case g.Status != "" && g.Status != StatusActive:
+		return g.Status
+	case !g.StartDateTime.IsZero() && now.Before(g.StartDateTime):
+		return StatusScheduled
+	case !g.ExpirationDate.IsZero() && !now.Before(g.ExpirationDate):
+		return StatusExpired
+	case g.UsageLimit > 0 && g.UsedCount >= g.UsageLimit, g.BalanceMode && g.RemainingBalance <= 0:
+		return StatusExhausted
+	}
+	return StatusActive
 }
 
 // Create inserts a new gift into the storage.
 func (s Storage) Create(g *Gift) error {
 	sqlStmt := `
-	INSERT INTO gift (code, gift_amount, usage_limit, used_count, expiration_date, start_date_time)
-	VALUES ($1, $2, $3, $4, $5, $6) 
-	                     RETURNING id, code, created_at, updated_at`
-	err := s.db.QueryRow(sqlStmt, g.Code, g.GiftAmount, g.UsageLimit, g.UsedCount, g.ExpirationDate,
-		g.StartDateTime).Scan(&g.ID, &g.Code, &g.CreatedAt, &g.UpdatedAt)
+	INSERT INTO gift (code, gift_amount, usage_limit, used_count, expiration_date, start_date_time, balance_mode,
+	                  remaining_balance, exclusive, stackable_with_gift, stackable_with_discount, campaign_id, schedule,
+	                  status, job_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) 
+	                     RETURNING id, code, created_at, updated_at, version`
+	err := s.db.QueryRow(sqlStmt, g.insertArgs()...).Scan(&g.ID, &g.Code, &g.CreatedAt, &g.UpdatedAt, &g.Version)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// InsertBatch inserts the gifts in one statement and returns how many were inserted. Gifts whose code is already
+// taken are skipped instead of failing the whole batch.
+func (s Storage) InsertBatch(gifts []*Gift) (int64, error) {
+	if len(gifts) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(gifts)*giftInsertColumns)
+	for _, g := range gifts {
+		args = append(args, g.insertArgs()...)
+	}
+	sqlStmt := `
+	INSERT INTO gift (code, gift_amount, usage_limit, used_count, expiration_date, start_date_time, balance_mode,
+	                  remaining_balance, exclusive, stackable_with_gift, stackable_with_discount, campaign_id, schedule,
+	                  status, job_id)
+	VALUES ` + db.ValuesPlaceholders(len(gifts), giftInsertColumns) + `
+	ON CONFLICT (code) DO NOTHING`
+	row, err := s.db.Exec(sqlStmt, args...)
+	if err != nil {
+		return 0, serr.DBError("InsertBatch", "gift", err)
+	}
+	return row.RowsAffected()
+}
+
+// ForEachByJob calls fn for every gift created by the bulk generation job, in the order they were inserted.
+// The gifts are read from a cursor one by one, so the job may be arbitrarily large.
+func (s Storage) ForEachByJob(jobID int64, fn func(g *Gift) error) error {
+	rows, err := s.db.Query("SELECT "+giftColumns+" FROM gift WHERE job_id = $1 AND deleted_at IS NULL ORDER BY id", jobID)
+	if err != nil {
+		return serr.DBError("ForEachByJob", "gift", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		g, err := s.scanGift(rows)
+		if err != nil {
+			return serr.DBError("ForEachByJob", "gift", err)
+		}
+		if err = fn(g); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ForEach calls fn for every gift matching the filter, in the order they were created. The gifts are read
+// from a cursor one by one, so any number of them can be gone through.
+func (s Storage) ForEach(f *Filter, now time.Time, fn func(g *Gift) error) error {
+	c, err := f.conditions(now)
+	if err != nil {
+		return err
+	}
+	rows, err := s.db.Query("SELECT "+giftColumns+" FROM gift"+c.Where()+" ORDER BY id", c.Args()...)
+	if err != nil {
+		return serr.DBError("ForEach", "gift", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		g, err := s.scanGift(rows)
+		if err != nil {
+			return serr.DBError("ForEach", "gift", err)
+		}
+		if err = fn(g); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (f *Filter) conditions(now time.Time) (*db.Conditions, error) {
+	c := &db.Conditions{}
+	c.Add("deleted_at IS NULL")
+	if !f.From.IsZero() {
+		c.Add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		c.Add("created_at < ?", f.To)
+	}
+	if f.Prefix != "" {
+		c.Add("code LIKE ?", db.LikePrefix(f.Prefix))
+	}
+	if f.MinAmount > 0 {
+		c.Add("gift_amount >= ?", f.MinAmount)
+	}
+	if f.MaxAmount > 0 {
+		c.Add("gift_amount <= ?", f.MaxAmount)
+	}
+	if !f.ExpiresFrom.IsZero() {
+		c.Add("expiration_date >= ?", f.ExpiresFrom)
+	}
+	if !f.ExpiresTo.IsZero() {
+		c.Add("expiration_date > ? AND expiration_date < ?", time.Time{}, f.ExpiresTo)
+	}
+	if f.CampaignID > 0 {
+		c.Add("campaign_id = ?", f.CampaignID)
+	}
+	switch f.Status {
+	case "":
+	case StatusDraft, StatusPaused, StatusArchived:
+		c.Add("status = ?", f.Status)
+	case StatusScheduled:
+		c.Add("status = ? AND start_date_time > ?", StatusActive, now)
+	case StatusExpired:
+		c.Add("status = ? AND expiration_date > ? AND expiration_date <= ?", StatusActive, time.Time{}, now)
+	case StatusExhausted:
+		c.Add("status = ? AND "+exhaustedCondition, StatusActive)
+	case StatusActive:
+		c.Add("status = ? AND start_date_time <= ? AND (expiration_date <= ? OR expiration_date > ?) AND NOT "+
+			exhaustedCondition, StatusActive, now, time.Time{}, now)
+	default:
+		return nil, serr.ValidationErr("status", "invalid status", serr.ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+// ExistingCodes returns which of the codes are already taken by a gift. Deleted gifts keep their codes until they
+// are purged.
+func (s Storage) ExistingCodes(codes []string) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT code FROM gift WHERE code = ANY($1)", pq.Array(codes))
+	if err != nil {
+		return nil, serr.DBError("ExistingCodes", "gift", err)
+	}
+	defer rows.Close()
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var code string
+		if err = rows.Scan(&code); err != nil {
+			return nil, serr.DBError("ExistingCodes", "gift", err)
+		}
+		existing[code] = true
+	}
+	return existing, rows.Err()
+}
+
+// CountLike returns how many gifts have a code matching the SQL LIKE expression.
+func (s Storage) CountLike(like string) (int64, error) {
+	var count int64
+	err := s.db.QueryRow("SELECT count(*) FROM gift WHERE code LIKE $1", like).Scan(&count)
+	if err != nil {
+		return 0, serr.DBError("CountLike", "gift", err)
+	}
+	return count, nil
+}
+
 // CreateBulk inserts a new gift into the storage.
 func (s Storage) CreateBulk(gifts []*Gift) error {
 	for _, gift := range gifts {
@@ -53,34 +309,46 @@ func (s Storage) CreateBulk(gifts []*Gift) error {
 	return nil
 }
 
-// UpdateDirectDb updates the gift in the storage. It first calls the SyncRedisWithDB method to synchronize the data with Redis
-// and the database. If there is an error during synchronization
+// UpdateDirectDb updates the gift in the database and evicts it from Redis, so the next use reads the update.
+// The usages held in Redis are written to the database first.
 func (s Storage) UpdateDirectDb(g *Gift) error {
-	err := s.SyncRedisWithDB()
-	if err != nil {
-		log.Err(err).Msg("SyncRedisWithDB")
-	}
+	return s.evict(g.Code, func() error {
+		return s.Update(g)
+	})
+}
+
+// Update writes the gift to the database if it is still at g.Version, and raises its version. The used count and
+// remaining balance are left as they are, since they are kept by uses rather than by updates. A gift that has been
+// updated since g was read fails with a version mismatch.
+func (s Storage) Update(g *Gift) error {
 	sqlStmt := `
-	UPDATE gift SET code = $1, gift_amount = $2, usage_limit = $3, used_count = $4, 
-	                   expiration_date = $5, start_date_time = $6, updated_at = now()
-	WHERE id = $7 RETURNING updated_at`
-	err = s.db.QueryRow(sqlStmt, g.Code, g.GiftAmount, g.UsageLimit, g.UsedCount, g.ExpirationDate,
-		g.StartDateTime, g.ID).Scan(&g.UpdatedAt)
+	UPDATE gift SET code = $1, gift_amount = $2, usage_limit = $3, expiration_date = $4, start_date_time = $5,
+	                   balance_mode = $6, exclusive = $7, stackable_with_gift = $8, stackable_with_discount = $9,
+	                   campaign_id = $10, schedule = $11, version = version + 1, updated_at = now()
+	WHERE id = $12 AND version = $13 AND deleted_at IS NULL
+	RETURNING used_count, remaining_balance, version, updated_at`
+	err := s.db.QueryRow(sqlStmt, g.Code, g.GiftAmount, g.UsageLimit, g.ExpirationDate, g.StartDateTime,
+		g.BalanceMode, g.Exclusive, g.StackableWithGift, g.StackableWithDiscount, g.CampaignID, g.Schedule, g.ID,
+		g.Version).Scan(&g.UsedCount, &g.RemainingBalance, &g.Version, &g.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err = s.GetByID(g.ID); err != nil {
+			return err
+		}
+		return serr.PreconditionFailedErr("version", "version mismatch", serr.ErrVersionMismatch)
+	}
 	if err != nil {
-		return err
+		return serr.DBError("Update", "gift", err)
 	}
 	return nil
 }
 
-func (s Storage) Update(g *Gift) error {
-	sqlStmt := `
-	UPDATE gift SET code = $1, gift_amount = $2, usage_limit = $3, used_count = $4, 
-	                   expiration_date = $5, start_date_time = $6, updated_at = now()
-	WHERE id = $7 RETURNING updated_at`
-	err := s.db.QueryRow(sqlStmt, g.Code, g.GiftAmount, g.UsageLimit, g.UsedCount, g.ExpirationDate,
-		g.StartDateTime, g.ID).Scan(&g.UpdatedAt)
+// writeUsage writes the used count and remaining balance of a gift updated in Redis to the database. Uses change
+// nothing else, so a copy read before an update never overwrites the newer version.
+func (s Storage) writeUsage(g *Gift) error {
+	sqlStmt := "UPDATE gift SET used_count = $1, remaining_balance = $2, updated_at = now() WHERE id = $3"
+	_, err := s.db.Exec(sqlStmt, g.UsedCount, g.RemainingBalance, g.ID)
 	if err != nil {
-		return err
+		return serr.DBError("writeUsage", "gift", err)
 	}
 	return nil
 }
@@ -98,12 +366,13 @@ func (s Storage) GetByCode(code string) (*Gift, error) {
 		key := fmt.Sprintf(giftPrefix, code)
 		g, err = s.retrieveGiftFromRedis(key)
 		if err != nil {
-			gift := &Gift{}
-			sqlStmt := "SELECT " + giftColumns + " FROM gift WHERE code = $1"
-			err := s.db.QueryRow(sqlStmt, code).Scan(&gift.ID, &gift.Code, &gift.GiftAmount, &gift.UsageLimit, &gift.UsedCount,
-				&gift.ExpirationDate, &gift.StartDateTime, &gift.CreatedAt, &gift.UpdatedAt)
+			sqlStmt := "SELECT " + giftColumns + " FROM gift WHERE code = $1 AND deleted_at IS NULL"
+			gift, err := s.scanGift(s.db.QueryRow(sqlStmt, code))
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, serr.ValidationErr("code", "invalid gift code", serr.ErrInvalidGiftCode)
+			}
 			if err != nil {
-				return nil, serr.ValidationErr("code", "invalid discount code", serr.ErrInvalidGiftCode)
+				return nil, serr.DBError("GetByCode", "gift", err)
 			}
 			err = s.updateOrInsertGiftInRedis(key, gift, time.Minute*10)
 			if err != nil {
@@ -117,10 +386,8 @@ func (s Storage) GetByCode(code string) (*Gift, error) {
 }
 
 func (s Storage) GetByID(id int64) (*Gift, error) {
-	gift := &Gift{}
-	sqlStmt := "SELECT " + giftColumns + " FROM gift WHERE id = $1"
-	err := s.db.QueryRow(sqlStmt, id).Scan(&gift.ID, &gift.Code, &gift.GiftAmount, &gift.UsageLimit, &gift.UsedCount,
-		&gift.ExpirationDate, &gift.StartDateTime, &gift.CreatedAt, &gift.UpdatedAt)
+	sqlStmt := "SELECT " + giftColumns + " FROM gift WHERE id = $1 AND deleted_at IS NULL"
+	gift, err := s.scanGift(s.db.QueryRow(sqlStmt, id))
 	if err != nil {
 		return nil, serr.ValidationErr("code", "gift", serr.ErrInvalidGiftID)
 	}
@@ -128,29 +395,180 @@ func (s Storage) GetByID(id int64) (*Gift, error) {
 }
 
 // IncreaseUsedCountRedis increases the used count of a gift in Redis cache.
+// Usages held by active reservations count toward the usage limit. The check and the increment run in one Redis
+// transaction, so concurrent uses cannot push the gift past its limit.
 // Consider that the gift save in Redis AOF to prevent data loss.
 func (s Storage) IncreaseUsedCountRedis(code string) (*Gift, error) {
 	keyUpdate := fmt.Sprintf(giftPrefixUpdate, code)
-	gift, err := s.GetByCode(code)
+	keyHold := fmt.Sprintf(giftPrefixHold, code)
+	var gift *Gift
+	err := s.watch(func(tx *redis.Tx) error {
+		var err error
+		gift, err = s.GetByCode(code)
+		if err != nil {
+			return err
+		}
+		if gift.BalanceMode {
+			return errBalanceMode()
+		}
+		err = s.loadStatus(gift)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = gift.Validate(now)
+		if err != nil {
+			return err
+		}
+		err = s.checkHeldUsages(tx, gift, now)
+		if err != nil {
+			return err
+		}
+		gift.UsedCount++
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), keyUpdate, gift, 0)
+			return nil
+		})
+		return err
+	}, keyUpdate, keyHold)
 	if err != nil {
 		return nil, err
 	}
+	return gift, nil
+}
 
-	if gift.UsageLimit > 0 && gift.UsedCount+1 > gift.UsageLimit {
-		return nil, serr.ValidationErr("code", "gift usage limit reached", serr.ErrGiftUsageLimitReached)
+// DebitBalanceRedis takes amount off the remaining balance of a balance mode gift in Redis cache and counts it
+// as one use. Like IncreaseUsedCountRedis the check and the debit run in one Redis transaction, so concurrent
+// debits cannot take the balance below zero.
+func (s Storage) DebitBalanceRedis(code string, amount int64) (*Gift, error) {
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, code)
+	var gift *Gift
+	err := s.watch(func(tx *redis.Tx) error {
+		var err error
+		gift, err = s.GetByCode(code)
+		if err != nil {
+			return err
+		}
+		if !gift.BalanceMode {
+			return serr.ValidationErr("code", "gift is not a balance gift", serr.ErrGiftNotBalanceMode)
+		}
+		err = s.loadStatus(gift)
+		if err != nil {
+			return err
+		}
+		err = gift.Validate(time.Now())
+		if err != nil {
+			return err
+		}
+		if amount > gift.RemainingBalance {
+			return serr.ValidationErr("amount", "insufficient gift balance", serr.ErrInsufficientGiftBalance)
+		}
+		gift.RemainingBalance -= amount
+		gift.UsedCount++
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), keyUpdate, gift, 0)
+			return nil
+		})
+		return err
+	}, keyUpdate)
+	if err != nil {
+		return nil, err
 	}
-	gift.UsedCount++
-	err = s.updateOrInsertGiftInRedis(keyUpdate, gift, 0)
+	return gift, nil
+}
+
+// DecreaseUsedCountRedis restores one usage of a gift in Redis cache, and credits amount back to the remaining
+// balance of a balance mode gift. Like IncreaseUsedCountRedis it only updates the UPDATED_GIFT copy, which
+// SyncRedisWithDB then writes to the database. Deleted gifts are not kept in Redis, so their usage is restored in
+// the database, and uses made before a gift was deleted can still be reversed.
+func (s Storage) DecreaseUsedCountRedis(code string, amount int64) (*Gift, error) {
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, code)
+	var gift *Gift
+	err := s.watch(func(tx *redis.Tx) error {
+		var err error
+		gift, err = s.GetByCode(code)
+		var e *serr.ServiceError
+		if errors.As(err, &e) && e.ErrorCode == serr.ErrInvalidGiftCode {
+			gift, err = s.changeDeletedUsage(code, -1, amount)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if gift.UsedCount <= 0 {
+			return serr.ValidationErr("code", "gift has no usage to restore", serr.ErrNoUsageToRestore)
+		}
+		gift.UsedCount--
+		if gift.BalanceMode {
+			gift.RemainingBalance += amount
+		}
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), keyUpdate, gift, 0)
+			return nil
+		})
+		return err
+	}, keyUpdate)
 	if err != nil {
 		return nil, err
 	}
 	return gift, nil
 }
 
+// RevertDecreaseUsedCountRedis takes back a usage restored by DecreaseUsedCountRedis when the reversal it was
+// restored for fails afterwards. The usage was counted before, so unlike IncreaseUsedCountRedis the gift is not
+// checked against its status and limits. Like DecreaseUsedCountRedis it changes deleted gifts in the database.
+func (s Storage) RevertDecreaseUsedCountRedis(code string, amount int64) error {
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, code)
+	return s.watch(func(tx *redis.Tx) error {
+		gift, err := s.GetByCode(code)
+		var e *serr.ServiceError
+		if errors.As(err, &e) && e.ErrorCode == serr.ErrInvalidGiftCode {
+			_, err = s.changeDeletedUsage(code, 1, -amount)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		gift.UsedCount++
+		if gift.BalanceMode {
+			gift.RemainingBalance -= amount
+		}
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Set(context.Background(), keyUpdate, gift, 0)
+			return nil
+		})
+		return err
+	}, keyUpdate)
+}
+
+// changeDeletedUsage adds uses to the used count of a deleted gift in the database and amount to the remaining
+// balance of a balance mode one, for DecreaseUsedCountRedis and RevertDecreaseUsedCountRedis.
+func (s Storage) changeDeletedUsage(code string, uses, amount int64) (*Gift, error) {
+	sqlStmt := `
+	UPDATE gift SET used_count = used_count + $2,
+	                remaining_balance = remaining_balance + CASE WHEN balance_mode THEN $3 ELSE 0 END, updated_at = now()
+	WHERE code = $1 AND deleted_at IS NOT NULL AND used_count + $2 >= 0 RETURNING ` + giftColumns
+	g, err := s.scanGift(s.db.QueryRow(sqlStmt, code, uses, amount))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.db.QueryRow("SELECT id FROM gift WHERE code = $1 AND deleted_at IS NOT NULL", code).Scan(new(int64))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, serr.ValidationErr("code", "invalid gift code", serr.ErrInvalidGiftCode)
+		}
+		if err != nil {
+			return nil, serr.DBError("changeDeletedUsage", "gift", err)
+		}
+		return nil, serr.ValidationErr("code", "gift has no usage to restore", serr.ErrNoUsageToRestore)
+	}
+	if err != nil {
+		return nil, serr.DBError("changeDeletedUsage", "gift", err)
+	}
+	return g, nil
+}
+
 func (s Storage) IncreaseUsedCount(code string) error {
 	sqlStmt := `
 	UPDATE gift SET used_count = used_count + 1, updated_at = now()
-	WHERE code = $1 RETURNING updated_at`
+	WHERE code = $1 AND deleted_at IS NULL RETURNING updated_at`
 	row, err := s.db.Exec(sqlStmt, code)
 	if err != nil {
 		return err
@@ -161,31 +579,53 @@ func (s Storage) IncreaseUsedCount(code string) error {
 	return nil
 }
 
+// Delete marks the gift as deleted. See DeleteByCode.
 func (s Storage) Delete(id int64) error {
 	g, err := s.GetByID(id)
-	s.removeGiftFromRedis(g)
-	sqlStmt := "DELETE FROM gift WHERE id = $1"
-	row, err := s.db.Exec(sqlStmt, id)
 	if err != nil {
-		return err
-	}
-	if count, err := row.RowsAffected(); err != nil || count == 0 {
 		return ErrNoRowToUpdate
 	}
-	return nil
+	return s.DeleteByCode(g.Code)
 }
 
+// DeleteByCode marks the gift as deleted and evicts it from Redis. Deleted gifts are left out of every read and
+// can be restored until PurgeDeleted removes them for good.
 func (s Storage) DeleteByCode(code string) error {
-	s.removeGiftFromRedis(&Gift{Code: code})
-	sqlStmt := "DELETE FROM gift WHERE code = $1"
-	row, err := s.db.Exec(sqlStmt, code)
+	return s.evict(code, func() error {
+		sqlStmt := "UPDATE gift SET deleted_at = now() WHERE code = $1 AND deleted_at IS NULL"
+		row, err := s.db.Exec(sqlStmt, code)
+		if err != nil {
+			return err
+		}
+		if count, err := row.RowsAffected(); err != nil || count == 0 {
+			return ErrNoRowToUpdate
+		}
+		return nil
+	})
+}
+
+// Restore brings back a deleted gift.
+func (s Storage) Restore(code string) (*Gift, error) {
+	sqlStmt := `
+	UPDATE gift SET deleted_at = NULL, updated_at = now()
+	WHERE code = $1 AND deleted_at IS NOT NULL RETURNING ` + giftColumns
+	g, err := s.scanGift(s.db.QueryRow(sqlStmt, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("code", "deleted gift not found", serr.ErrDeletedCodeNotFound)
+	}
 	if err != nil {
-		return err
+		return nil, serr.DBError("Restore", "gift", err)
 	}
-	if count, err := row.RowsAffected(); err != nil || count == 0 {
-		return ErrNoRowToUpdate
+	return g, nil
+}
+
+// PurgeDeleted removes the gifts deleted before t from the database for good and returns how many were removed.
+func (s Storage) PurgeDeleted(t time.Time) (int64, error) {
+	row, err := s.db.Exec("DELETE FROM gift WHERE deleted_at < $1", t)
+	if err != nil {
+		return 0, serr.DBError("PurgeDeleted", "gift", err)
 	}
-	return nil
+	return row.RowsAffected()
 }
 
 func (s Storage) DeleteBulkByIDs(ids []int64) error {
@@ -200,7 +640,6 @@ func (s Storage) DeleteBulkByIDs(ids []int64) error {
 
 func (s Storage) DeleteBulkByCodes(codes []string) error {
 	for _, code := range codes {
-		s.removeGiftFromRedis(&Gift{Code: code})
 		err := s.DeleteByCode(code)
 		if err != nil {
 			return err
@@ -209,19 +648,31 @@ func (s Storage) DeleteBulkByCodes(codes []string) error {
 	return nil
 }
 
-func (s Storage) getAllByPage(limit, offset int, count bool) ([]*Gift, int, error) {
+// GetAllByPage returns the gifts matching the filter page by page, in the given order or else newest first. Ties
+// are broken by id, so pages do not overlap.
+func (s Storage) GetAllByPage(
+	f *Filter, orders []Order, now time.Time, limit, offset int, count bool,
+) ([]*Gift, int, error) {
+	c, err := f.conditions(now)
+	if err != nil {
+		return nil, 0, err
+	}
+	order, err := orderBy(orders)
+	if err != nil {
+		return nil, 0, err
+	}
 	var total int
 	if count {
-		err := s.db.QueryRow("SELECT count(*) FROM gift").Scan(&total)
+		err = s.db.QueryRow("SELECT count(*) FROM gift"+c.Where(), c.Args()...).Scan(&total)
 		if err != nil {
 			return nil, 0, serr.DBError("List", "gift", err)
 		}
 	}
-	pagination := fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
-	order := " ORDER BY created_at DESC"
-	rows, err := s.db.Query("SELECT " + giftColumns + " FROM gift" + order + pagination)
+	args := append(c.Args(), limit, offset)
+	pagination := fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	rows, err := s.db.Query("SELECT "+giftColumns+" FROM gift"+c.Where()+order+pagination, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, serr.DBError("List", "gift", err)
 	}
 	defer rows.Close()
 	gifts := make([]*Gift, 0)
@@ -232,33 +683,79 @@ func (s Storage) getAllByPage(limit, offset int, count bool) ([]*Gift, int, erro
 		}
 		gifts = append(gifts, g)
 	}
-	return gifts, total, nil
+	return gifts, total, rows.Err()
+}
+
+// orderBy returns the ORDER BY clause sorting by the given orders, then by id.
+func orderBy(orders []Order) (string, error) {
+	if len(orders) == 0 {
+		return " ORDER BY created_at DESC, id DESC", nil
+	}
+	terms := make([]string, 0, len(orders)+1)
+	for _, o := range orders {
+		column, ok := sortColumns[o.Field]
+		if !ok {
+			return "", serr.ValidationErr("sort", "invalid sort field", serr.ErrInvalidFilter)
+		}
+		if o.Desc {
+			column += " DESC"
+		}
+		terms = append(terms, column)
+	}
+	return " ORDER BY " + strings.Join(append(terms, "id"), ", "), nil
 }
 
+// SyncRedisWithDB writes the usages of the gifts updated in Redis to the database and removes them from Redis.
+// A gift that is used again while it is being written stays in Redis and is written again.
 func (s Storage) SyncRedisWithDB() error {
 	keys, err := s.redis.Keys(context.Background(), "UPDATED_GIFT:*").Result()
 	if err != nil {
 		return err
 	}
 	for _, key := range keys {
-		g, err := s.retrieveGiftFromRedis(key)
-		if err != nil {
+		err = s.watch(func(tx *redis.Tx) error {
+			g, err := s.retrieveGiftFromRedis(key)
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = s.writeUsage(g)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+				pipe.Del(context.Background(), key, fmt.Sprintf(giftPrefix, g.Code))
+				return nil
+			})
 			return err
-		}
-		err = s.Update(g)
+		}, key)
 		if err != nil {
 			return err
 		}
-		s.removeGiftFromRedis(g)
-		return nil
 	}
 	return nil
 }
 
+// watch runs fn in a Redis transaction watching the given keys, retrying it when one of them changes before
+// the transaction is committed.
+func (s Storage) watch(fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxTxRetries; i++ {
+		err := s.redis.Watch(context.Background(), fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrTooManyRetries
+}
+
 func (s Storage) scanGift(scanner db.Scanner) (*Gift, error) {
 	g := &Gift{}
 	err := scanner.Scan(&g.ID, &g.Code, &g.GiftAmount, &g.UsageLimit, &g.UsedCount, &g.ExpirationDate,
-		&g.StartDateTime, &g.CreatedAt, &g.UpdatedAt)
+		&g.StartDateTime, &g.BalanceMode, &g.RemainingBalance, &g.Exclusive, &g.StackableWithGift,
+		&g.StackableWithDiscount, &g.CampaignID, &g.Schedule, &g.Status, &g.JobID, &g.CreatedAt, &g.UpdatedAt,
+		&g.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -286,17 +783,62 @@ func (s Storage) retrieveGiftFromRedis(key string) (*Gift, error) {
 	return gift, nil
 }
 
-func (s Storage) removeGiftFromRedis(g *Gift) {
-	key := fmt.Sprintf(giftPrefix, g.Code)
-	keyUpdate := fmt.Sprintf(giftPrefixUpdate, g.Code)
-	s.removeWithKey(key)
-	s.removeWithKey(keyUpdate)
+// Evict runs fn, which changes the gift in the database, and drops the gift from Redis the way the storage does for
+// its own changes. It lets a caller change the gift together with other records in one database transaction: fn
+// commits the transaction, and changes made through a storage bound to it leave the eviction to Evict.
+func (s Storage) Evict(code string, fn func() error) error {
+	return s.evict(code, fn)
+}
+
+// evict runs fn, which changes the gift in the database, and drops the gift from Redis. The usages of a copy of the
+// gift updated in Redis are written to the database before fn runs, and both copies are dropped in one Redis
+// transaction, so no use is lost and the next read comes from the database. fn runs once, even when the
+// transaction is retried. A storage bound to a transaction only runs fn, since the change cannot be read before the
+// transaction commits; see Evict.
+func (s Storage) evict(code string, fn func() error) error {
+	if _, ok := s.db.(*sql.Tx); ok {
+		return fn()
+	}
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, code)
+	done := false
+	return s.watch(func(tx *redis.Tx) error {
+		updated, err := s.retrieveGiftFromRedis(keyUpdate)
+		switch {
+		case errors.Is(err, redis.Nil):
+		case err != nil:
+			return err
+		default:
+			if err = s.writeUsage(updated); err != nil {
+				return err
+			}
+		}
+		if !done {
+			if err = fn(); err != nil {
+				return err
+			}
+			done = true
+		}
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.Del(context.Background(), keyUpdate, fmt.Sprintf(giftPrefix, code))
+			return nil
+		})
+		return err
+	}, keyUpdate)
 }
 
 func (s Storage) removeWithKey(k string) {
 	s.redis.Del(context.Background(), k)
 }
 
+// giftInsertColumns is the number of values insertArgs returns for each gift.
+const giftInsertColumns = 15
+
+func (g *Gift) insertArgs() []any {
+	return []any{g.Code, g.GiftAmount, g.UsageLimit, g.UsedCount, g.ExpirationDate, g.StartDateTime, g.BalanceMode,
+		g.RemainingBalance, g.Exclusive, g.StackableWithGift, g.StackableWithDiscount, g.CampaignID, g.Schedule,
+		g.Status, g.JobID}
+}
+
 func (g *Gift) MarshalBinary() ([]byte, error) {
 	return json.Marshal(g)
 }
@@ -2,7 +2,9 @@ package gift_test
 
 import (
 	"database/sql"
+	"discount/internal/serr"
 	"discount/storage/gift"
+	"errors"
 	"github.com/redis/go-redis/v9"
 	"log"
 	"testing"
@@ -103,6 +105,84 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateVersionMismatch(t *testing.T) {
+	storage := setup()
+
+	g := &gift.Gift{
+		Code:           "TEST",
+		GiftAmount:     100,
+		UsageLimit:     10,
+		ExpirationDate: time.Now().AddDate(0, 0, 10),
+		StartDateTime:  time.Now(),
+	}
+
+	err := storage.Create(g)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stale := *g
+	g.GiftAmount = 200
+	err = storage.Update(g)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if g.Version != stale.Version+1 {
+		t.Fatalf("expected version %v, got %v", stale.Version+1, g.Version)
+	}
+
+	stale.GiftAmount = 300
+	err = storage.Update(&stale)
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrVersionMismatch {
+		t.Fatalf("expected %v, got %v", serr.ErrVersionMismatch, err)
+	}
+
+	err = storage.Delete(g.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPauseDuringUse(t *testing.T) {
+	storage := setup()
+
+	g := &gift.Gift{
+		Code:           "TEST",
+		GiftAmount:     100,
+		UsageLimit:     10,
+		ExpirationDate: time.Now().AddDate(0, 0, 10),
+		StartDateTime:  time.Now(),
+	}
+
+	err := storage.Create(g)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Cache the active gift, then pause it in the database only, as happens when a use reads the cache just
+	// before SetStatus evicts it.
+	_, err = storage.GetByCode(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_, err = openDB().Exec("UPDATE gift SET status = $1 WHERE id = $2", gift.StatusPaused, g.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = storage.IncreaseUsedCountRedis(g.Code)
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrGiftPaused {
+		t.Fatalf("expected %v, got %v", serr.ErrGiftPaused, err)
+	}
+
+	err = storage.Delete(g.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	storage := setup()
 	//defer teardown(storage) // cleanup your storage here
@@ -125,6 +205,26 @@ func TestDelete(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+
+	_, err = storage.GetByCode(g.Code)
+	if err == nil {
+		t.Fatalf("expected deleted gift to be hidden")
+	}
+
+	_, err = storage.Restore(g.Code)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err = storage.Delete(g.ID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err = storage.PurgeDeleted(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 }
 
 func TestGetByCode(t *testing.T) {
@@ -160,12 +260,115 @@ func TestGetByCode(t *testing.T) {
 	}
 }
 
-func setup() *gift.Storage {
-	// Initialize your database connection
+func TestGetAllByPage(t *testing.T) {
+	storage := setup()
+
+	gifts := []*gift.Gift{
+		{Code: "TESTLIST1", GiftAmount: 100, ExpirationDate: time.Now().AddDate(0, 0, 10)},
+		{Code: "TESTLIST2", GiftAmount: 300, ExpirationDate: time.Now().AddDate(0, 0, 10)},
+		{Code: "TESTLIST3", GiftAmount: 200, ExpirationDate: time.Now().AddDate(0, 0, 10)},
+	}
+	err := storage.CreateBulk(gifts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	f := &gift.Filter{Prefix: "TESTLIST", MinAmount: 200}
+	orders := []gift.Order{{Field: "giftAmount", Desc: true}}
+	got, total, err := storage.GetAllByPage(f, orders, time.Now(), 10, 0, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 2 || len(got) != 2 {
+		t.Fatalf("expected 2 gifts, got %v of %v", len(got), total)
+	}
+	if got[0].Code != "TESTLIST2" || got[1].Code != "TESTLIST3" {
+		t.Fatalf("expected TESTLIST2 and TESTLIST3, got %v and %v", got[0].Code, got[1].Code)
+	}
+
+	_, _, err = storage.GetAllByPage(f, []gift.Order{{Field: "id; DROP TABLE gift"}}, time.Now(), 10, 0, false)
+	var e *serr.ServiceError
+	if !errors.As(err, &e) || e.ErrorCode != serr.ErrInvalidFilter {
+		t.Fatalf("expected %v, got %v", serr.ErrInvalidFilter, err)
+	}
+
+	for _, g := range gifts {
+		err = storage.Delete(g.ID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		gift      gift.Gift
+		wantError serr.ErrorCode
+	}{
+		{
+			name: "valid",
+			gift: gift.Gift{Code: "TEST", UsageLimit: 10, StartDateTime: now.Add(-time.Hour), ExpirationDate: now.Add(time.Hour)},
+		},
+		{
+			name: "no date bounds",
+			gift: gift.Gift{Code: "TEST"},
+		},
+		{
+			name:      "not started",
+			gift:      gift.Gift{Code: "TEST", StartDateTime: now.Add(time.Hour)},
+			wantError: serr.ErrGiftNotStarted,
+		},
+		{
+			name:      "expired",
+			gift:      gift.Gift{Code: "TEST", ExpirationDate: now.Add(-time.Hour)},
+			wantError: serr.ErrGiftExpired,
+		},
+		{
+			name:      "usage limit reached",
+			gift:      gift.Gift{Code: "TEST", UsageLimit: 2, UsedCount: 2},
+			wantError: serr.ErrGiftUsageLimitReached,
+		},
+		{
+			name: "balance left",
+			gift: gift.Gift{Code: "TEST", BalanceMode: true, GiftAmount: 100, RemainingBalance: 30},
+		},
+		{
+			name:      "balance used up",
+			gift:      gift.Gift{Code: "TEST", BalanceMode: true, GiftAmount: 100},
+			wantError: serr.ErrGiftBalanceUsedUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.gift.Validate(now)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var e *serr.ServiceError
+			if !errors.As(err, &e) || e.ErrorCode != tt.wantError {
+				t.Fatalf("expected %v, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
+
+func openDB() *sql.DB {
 	db, err := sql.Open("postgres", "host=localhost port=5432 user=arv123 password=asd123ASD dbname=test sslmode=disable")
 	if err != nil {
 		log.Fatal(err)
 	}
+	return db
+}
+
+func setup() *gift.Storage {
+	// Initialize your database connection
+	db := openDB()
 
 	// Initialize your Redis client
 	redisClient := redis.NewClient(&redis.Options{
@@ -177,5 +380,8 @@ func setup() *gift.Storage {
 	// Create a new instance of gift.Storage
 	storage := gift.New(db, redisClient)
 
+	// Deleting only hides gifts, so purge the ones earlier tests left behind to free their codes.
+	_, _ = storage.PurgeDeleted(time.Now().Add(time.Minute))
+
 	return &storage
 }
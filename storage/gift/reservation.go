@@ -0,0 +1,188 @@
+package gift
+
+import (
+	"context"
+	"discount/internal/serr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// giftPrefixHold is a sorted set of the reservations holding usages of a gift, scored by their expiry.
+const giftPrefixHold = "GIFT_HOLD:%s"
+const reservationPrefix = "GIFT_RESERVATION:%s"
+
+// Reservation holds one usage of a gift until it is confirmed, cancelled or expires.
+type Reservation struct {
+	ID        string
+	Code      string
+	UserID    string
+	OrderID   string
+	Amount    int64
+	ExpiresAt time.Time
+}
+
+// Reserve holds one usage of the gift for the reservation until r.ExpiresAt. The gift must be usable and have
+// a usage left once the usages held by other active reservations are counted.
+func (s Storage) Reserve(r *Reservation) (*Gift, error) {
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, r.Code)
+	keyHold := fmt.Sprintf(giftPrefixHold, r.Code)
+	var gift *Gift
+	err := s.watch(func(tx *redis.Tx) error {
+		var err error
+		gift, err = s.GetByCode(r.Code)
+		if err != nil {
+			return err
+		}
+		if gift.BalanceMode {
+			return errBalanceMode()
+		}
+		err = s.loadStatus(gift)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = gift.Validate(now)
+		if err != nil {
+			return err
+		}
+		err = s.checkHeldUsages(tx, gift, now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.ZRemRangeByScore(context.Background(), keyHold, "-inf", exclusiveScore(now))
+			pipe.ZAdd(context.Background(), keyHold, redis.Z{Score: float64(r.ExpiresAt.UnixMilli()), Member: r.ID})
+			pipe.Set(context.Background(), fmt.Sprintf(reservationPrefix, r.ID), r, time.Until(r.ExpiresAt))
+			return nil
+		})
+		return err
+	}, keyUpdate, keyHold)
+	if err != nil {
+		return nil, err
+	}
+	return gift, nil
+}
+
+// ConfirmReservation turns the usage held by the reservation into a real use of the gift. The gift must still be
+// usable when the reservation is confirmed.
+func (s Storage) ConfirmReservation(id string) (*Reservation, *Gift, error) {
+	r, err := s.GetReservation(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyUpdate := fmt.Sprintf(giftPrefixUpdate, r.Code)
+	keyHold := fmt.Sprintf(giftPrefixHold, r.Code)
+	keyReservation := fmt.Sprintf(reservationPrefix, r.ID)
+	var gift *Gift
+	err = s.watch(func(tx *redis.Tx) error {
+		// The hold is checked again inside the transaction in case the reservation expired or was cancelled.
+		now := time.Now()
+		expiresAt, err := tx.ZScore(context.Background(), keyHold, r.ID).Result()
+		if errors.Is(err, redis.Nil) {
+			return errReservationNotFound()
+		}
+		if err != nil {
+			return err
+		}
+		if int64(expiresAt) <= now.UnixMilli() {
+			return errReservationNotFound()
+		}
+		gift, err = s.GetByCode(r.Code)
+		if err != nil {
+			return err
+		}
+		// The gift may have been paused, archived or left its validity window since the reservation was made.
+		err = s.loadStatus(gift)
+		if err != nil {
+			return err
+		}
+		err = gift.Validate(now)
+		if err != nil {
+			return err
+		}
+		gift.UsedCount++
+		_, err = tx.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+			pipe.ZRem(context.Background(), keyHold, r.ID)
+			pipe.Del(context.Background(), keyReservation)
+			pipe.Set(context.Background(), keyUpdate, gift, 0)
+			return nil
+		})
+		return err
+	}, keyUpdate, keyHold, keyReservation)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, gift, nil
+}
+
+// CancelReservation releases the usage held by the reservation.
+func (s Storage) CancelReservation(id string) (*Reservation, error) {
+	r, err := s.GetReservation(id)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := s.redis.ZRem(context.Background(), fmt.Sprintf(giftPrefixHold, r.Code), r.ID).Result()
+	if err != nil {
+		return nil, err
+	}
+	s.removeWithKey(fmt.Sprintf(reservationPrefix, r.ID))
+	if removed == 0 {
+		return nil, errReservationNotFound()
+	}
+	return r, nil
+}
+
+// GetReservation returns an active reservation. Expired reservations are not found.
+func (s Storage) GetReservation(id string) (*Reservation, error) {
+	v, err := s.redis.Get(context.Background(), fmt.Sprintf(reservationPrefix, id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, errReservationNotFound()
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Reservation{}
+	err = json.Unmarshal([]byte(v), r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkHeldUsages fails if the usages held by active reservations leave no usage of the gift.
+func (s Storage) checkHeldUsages(tx *redis.Tx, g *Gift, now time.Time) error {
+	if g.UsageLimit <= 0 {
+		return nil
+	}
+	held, err := tx.ZCount(context.Background(), fmt.Sprintf(giftPrefixHold, g.Code), exclusiveScore(now), "+inf").Result()
+	if err != nil {
+		return err
+	}
+	if g.UsedCount+held >= g.UsageLimit {
+		return serr.ValidationErr("code", "gift usage limit reached", serr.ErrGiftUsageLimitReached)
+	}
+	return nil
+}
+
+// errBalanceMode is returned when a balance mode gift is used as a one-shot voucher. Balance gifts are only
+// spent through DebitBalanceRedis.
+func errBalanceMode() error {
+	return serr.ValidationErr("code", "balance gift must be debited", serr.ErrGiftBalanceMode)
+}
+
+func errReservationNotFound() error {
+	return serr.ValidationErr("code", "reservation not found or expired", serr.ErrReservationNotFound)
+}
+
+// exclusiveScore is an exclusive sorted set bound at t.
+func exclusiveScore(t time.Time) string {
+	return "(" + strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+func (r *Reservation) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
@@ -0,0 +1,74 @@
+package gift
+
+import (
+	"database/sql"
+	"discount/internal/serr"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// Lifecycle statuses of a gift, next to StatusActive. A gift is created as a draft or active, can be paused and
+// resumed while it is active, and is archived once it must never be used again. Only active gifts can be used.
+const (
+	StatusDraft    = "draft"
+	StatusPaused   = "paused"
+	StatusArchived = "archived"
+)
+
+// transitions lists, for each status, the statuses a gift can be moved to it from. Archived gifts stay archived.
+var transitions = map[string][]string{
+	StatusActive:   {StatusDraft, StatusPaused},
+	StatusPaused:   {StatusActive},
+	StatusArchived: {StatusDraft, StatusActive, StatusPaused},
+}
+
+// SetStatus moves the gift to the given status if its current status allows it; a gift that already has the
+// status is left as is. Changing the status raises the version of the gift. The gift is evicted from Redis, so the
+// next use reads the new status.
+func (s Storage) SetStatus(code, status string) (*Gift, error) {
+	var gift *Gift
+	err := s.evict(code, func() error {
+		var err error
+		gift, err = s.updateStatus(code, status)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gift, nil
+}
+
+func (s Storage) updateStatus(code, status string) (*Gift, error) {
+	sqlStmt := `
+	UPDATE gift SET status = $1, version = version + CASE WHEN status = $1 THEN 0 ELSE 1 END, updated_at = now()
+	WHERE code = $2 AND deleted_at IS NULL AND (status = $1 OR status = ANY($3)) RETURNING ` + giftColumns
+	g, err := s.scanGift(s.db.QueryRow(sqlStmt, status, code, pq.Array(transitions[status])))
+	if !errors.Is(err, sql.ErrNoRows) {
+		if err != nil {
+			return nil, serr.DBError("SetStatus", "gift", err)
+		}
+		return g, nil
+	}
+	err = s.db.QueryRow("SELECT id FROM gift WHERE code = $1 AND deleted_at IS NULL", code).Scan(new(int64))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("code", "invalid gift code", serr.ErrInvalidGiftCode)
+	}
+	if err != nil {
+		return nil, serr.DBError("SetStatus", "gift", err)
+	}
+	return nil, serr.ConflictErr("status", "invalid status transition", serr.ErrInvalidStatusTransition)
+}
+
+// loadStatus sets the status of the gift to the one stored in the database. The copies of a gift in Redis can be
+// read just before SetStatus evicts them, so uses take the status from the database before they commit; a gift
+// that was deleted in the meantime is reported as an invalid code.
+func (s Storage) loadStatus(gift *Gift) error {
+	err := s.db.QueryRow("SELECT status FROM gift WHERE id = $1 AND deleted_at IS NULL", gift.ID).Scan(&gift.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return serr.ValidationErr("code", "invalid gift code", serr.ErrInvalidGiftCode)
+	}
+	if err != nil {
+		return serr.DBError("GetByCode", "gift", err)
+	}
+	return nil
+}
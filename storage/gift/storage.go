@@ -9,9 +9,13 @@ import (
 )
 
 var (
-	ErrNoRowToUpdate = errors.New("no row to update")
+	ErrNoRowToUpdate  = errors.New("no row to update")
+	ErrTooManyRetries = errors.New("too many concurrent updates")
 )
 
+// maxTxRetries is how many times a Redis transaction is retried when a watched key changes under it.
+const maxTxRetries = 10
+
 type Storage struct {
 	db    db.SQLExt
 	redis *redis.Client
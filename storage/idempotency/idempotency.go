@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const recordPrefix = "IDEMPOTENCY:%s:%s"
+const lockPrefix = "IDEMPOTENCY_LOCK:%s:%s"
+
+// Record is the stored response of a request made with an idempotency key. Request is a hash of the request, so
+// that the key cannot be reused for a different one.
+type Record struct {
+	Key        string    `db:"key"`
+	Scope      string    `db:"scope"`
+	Request    string    `db:"request"`
+	StatusCode int       `db:"status_code"`
+	Response   []byte    `db:"response"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Get returns the record stored for the key within the scope, or nil if there is none newer than the retention.
+// Redis is checked first; a record found only in the database is cached again for the rest of its retention.
+func (s Storage) Get(scope, key string, retention time.Duration) (*Record, error) {
+	redisKey := fmt.Sprintf(recordPrefix, scope, key)
+	r, err := s.retrieveRecordFromRedis(redisKey)
+	if err == nil {
+		return r, nil
+	}
+
+	r = &Record{}
+	sqlStmt := `
+	SELECT key, scope, request, status_code, response, created_at FROM idempotency_key
+	WHERE scope = $1 AND key = $2 AND created_at > $3`
+	err = s.db.QueryRow(sqlStmt, scope, key, time.Now().Add(-retention)).
+		Scan(&r.Key, &r.Scope, &r.Request, &r.StatusCode, &r.Response, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ttl := time.Until(r.CreatedAt.Add(retention)); ttl > 0 {
+		_ = s.redis.Set(context.Background(), redisKey, r, ttl).Err()
+	}
+	return r, nil
+}
+
+// Save stores the record in Redis and in the database for the given retention. Redis is written first, so the
+// response can be replayed from it even when the database write fails.
+func (s Storage) Save(r *Record, retention time.Duration) error {
+	r.CreatedAt = time.Now()
+	redisErr := s.redis.Set(context.Background(), fmt.Sprintf(recordPrefix, r.Scope, r.Key), r, retention).Err()
+	sqlStmt := `
+	INSERT INTO idempotency_key (key, scope, request, status_code, response)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (scope, key) DO UPDATE SET request = $3, status_code = $4, response = $5, created_at = now()
+	                     RETURNING created_at`
+	err := s.db.QueryRow(sqlStmt, r.Key, r.Scope, r.Request, r.StatusCode, string(r.Response)).Scan(&r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return redisErr
+}
+
+// Lock marks the key as being processed so that a concurrent request with the same key is not executed twice.
+// It returns false if the key is already locked.
+func (s Storage) Lock(scope, key string, ttl time.Duration) (bool, error) {
+	return s.redis.SetNX(context.Background(), fmt.Sprintf(lockPrefix, scope, key), 1, ttl).Result()
+}
+
+func (s Storage) Unlock(scope, key string) {
+	s.redis.Del(context.Background(), fmt.Sprintf(lockPrefix, scope, key))
+}
+
+// DeleteOlderThan removes the records created before t from the database.
+func (s Storage) DeleteOlderThan(t time.Time) error {
+	_, err := s.db.Exec("DELETE FROM idempotency_key WHERE created_at < $1", t)
+	return err
+}
+
+func (s Storage) retrieveRecordFromRedis(key string) (*Record, error) {
+	v, err := s.redis.Get(context.Background(), key).Result()
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{}
+	err = json.Unmarshal([]byte(v), r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Record) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
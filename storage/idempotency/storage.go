@@ -0,0 +1,16 @@
+package idempotency
+
+import (
+	"database/sql"
+	"discount/db"
+	"github.com/redis/go-redis/v9"
+)
+
+type Storage struct {
+	db    db.SQLExt
+	redis *redis.Client
+}
+
+func New(db *sql.DB, redis *redis.Client) Storage {
+	return Storage{db: db, redis: redis}
+}
@@ -0,0 +1,119 @@
+package job
+
+import (
+	"database/sql"
+	"discount/db"
+	"discount/internal/serr"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const jobColumns = "id,code_type,prefix,count,generated,status,error,template,created_at,updated_at,finished_at"
+
+// Statuses of a job. A job is pending until its first batch is generated, running until all its codes are, and
+// then completed, or failed when it stopped early. Jobs run in the process that created them, so a job that is not
+// updated for a while, such as one interrupted by a restart, is marked failed by FailStale rather than resumed.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Job is a bulk generation of Count gift or discount codes from one template. Generated is updated after every
+// batch, so it shows the progress of a running job.
+type Job struct {
+	ID         int64           `db:"id"`
+	CodeType   string          `db:"code_type"`
+	Prefix     string          `db:"prefix"`
+	Count      int64           `db:"count"`
+	Generated  int64           `db:"generated"`
+	Status     string          `db:"status"`
+	Error      string          `db:"error"`
+	Template   json.RawMessage `db:"template"`
+	CreatedAt  time.Time       `db:"created_at"`
+	UpdatedAt  time.Time       `db:"updated_at"`
+	FinishedAt *time.Time      `db:"finished_at"`
+}
+
+func (s Storage) Create(j *Job) error {
+	sqlStmt := `
+	INSERT INTO job (code_type, prefix, count, generated, status, template)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	                     RETURNING id, created_at, updated_at`
+	err := s.db.QueryRow(sqlStmt, j.CodeType, j.Prefix, j.Count, j.Generated, j.Status, []byte(j.Template)).
+		Scan(&j.ID, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s Storage) GetByID(id int64) (*Job, error) {
+	sqlStmt := "SELECT " + jobColumns + " FROM job WHERE id = $1"
+	j, err := s.scanJob(s.db.QueryRow(sqlStmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("id", "job not found", serr.ErrJobNotFound)
+	}
+	if err != nil {
+		return nil, serr.DBError("GetByID", "job", err)
+	}
+	return j, nil
+}
+
+// UpdateProgress records how many codes the job has generated so far and marks it as running. It returns
+// ErrJobFinished if the job has already finished, such as when FailStale marked it failed.
+func (s Storage) UpdateProgress(j *Job) error {
+	sqlStmt := `
+	UPDATE job SET generated = $2, status = $3, updated_at = now()
+	WHERE id = $1 AND status IN ($4, $3) RETURNING updated_at`
+	err := s.db.QueryRow(sqlStmt, j.ID, j.Generated, StatusRunning, StatusPending).Scan(&j.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobFinished
+	}
+	return err
+}
+
+// Finish records the final status of the job. errMsg explains why a failed job stopped. The final status is set
+// only once, so Finish returns ErrJobFinished and leaves the job as it is if it has already finished.
+func (s Storage) Finish(j *Job, status, errMsg string) error {
+	sqlStmt := `
+	UPDATE job SET generated = $2, status = $3, error = $4, updated_at = now(), finished_at = now()
+	WHERE id = $1 AND status IN ($5, $6) RETURNING updated_at, finished_at`
+	err := s.db.QueryRow(sqlStmt, j.ID, j.Generated, status, errMsg, StatusPending, StatusRunning).
+		Scan(&j.UpdatedAt, &j.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrJobFinished
+	}
+	if err != nil {
+		return err
+	}
+	j.Status, j.Error = status, errMsg
+	return nil
+}
+
+// FailStale marks the pending and running jobs last updated before t as failed with errMsg, and returns how many
+// were marked. The codes they generated are kept.
+func (s Storage) FailStale(t time.Time, errMsg string) (int64, error) {
+	sqlStmt := `
+	UPDATE job SET status = $1, error = $2, updated_at = now(), finished_at = now()
+	WHERE status IN ($3, $4) AND updated_at < $5`
+	row, err := s.db.Exec(sqlStmt, StatusFailed, errMsg, StatusPending, StatusRunning, t)
+	if err != nil {
+		return 0, serr.DBError("FailStale", "job", err)
+	}
+	return row.RowsAffected()
+}
+
+func (s Storage) scanJob(scanner db.Scanner) (*Job, error) {
+	j := &Job{}
+	var template []byte
+	err := scanner.Scan(&j.ID, &j.CodeType, &j.Prefix, &j.Count, &j.Generated, &j.Status, &j.Error, &template,
+		&j.CreatedAt, &j.UpdatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Template = template
+	return j, nil
+}
@@ -0,0 +1,33 @@
+package job
+
+import (
+	"database/sql"
+	"discount/db"
+	"errors"
+)
+
+var (
+	ErrJobFinished = errors.New("job has already finished")
+)
+
+type Storage struct {
+	db db.SQLExt
+}
+
+func New(db *sql.DB) Storage {
+	return Storage{db: db}
+}
+
+// WithTX returns a new storage with the given transaction replacing the db.
+func (s Storage) WithTX(tx *sql.Tx) (Storage, error) {
+	if tx == nil {
+		return Storage{}, db.ErrNoTXProvided
+	}
+	switch s.db.(type) {
+	case *sql.Tx:
+		return Storage{}, db.ErrAlreadyInTX
+	case *sql.DB:
+		return Storage{db: tx}, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,206 @@
+package redemption
+
+import (
+	"database/sql"
+	"discount/db"
+	"discount/internal/serr"
+	"errors"
+	"time"
+)
+
+const redemptionColumns = "id,code,code_type,user_id,order_id,amount,created_at,reversed_at"
+
+const (
+	CodeTypeGift     = "gift"
+	CodeTypeDiscount = "discount"
+)
+
+// Redemption is a single successful use of a gift or discount code.
+type Redemption struct {
+	ID         int64      `db:"id"`
+	Code       string     `db:"code"`
+	CodeType   string     `db:"code_type"`
+	UserID     string     `db:"user_id"`
+	OrderID    string     `db:"order_id"`
+	Amount     int64      `db:"amount"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ReversedAt *time.Time `db:"reversed_at"`
+}
+
+// Statuses a redemption can be filtered by.
+const (
+	StatusActive   = "active"
+	StatusReversed = "reversed"
+)
+
+// Filter narrows down the redemptions read by ForEach. From and To bound the redemption time, To exclusive;
+// zero fields do not filter.
+type Filter struct {
+	From     time.Time
+	To       time.Time
+	Prefix   string
+	Status   string
+	CodeType string
+}
+
+// Create inserts a new redemption record.
+func (s Storage) Create(r *Redemption) error {
+	sqlStmt := `
+	INSERT INTO redemption (code, code_type, user_id, order_id, amount)
+	VALUES ($1, $2, $3, $4, $5)
+	                     RETURNING id, created_at`
+	err := s.db.QueryRow(sqlStmt, r.Code, r.CodeType, r.UserID, r.OrderID, r.Amount).Scan(&r.ID, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s Storage) GetByID(id int64) (*Redemption, error) {
+	sqlStmt := "SELECT " + redemptionColumns + " FROM redemption WHERE id = $1"
+	r, err := s.scanRedemption(s.db.QueryRow(sqlStmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, serr.ValidationErr("id", "redemption not found", serr.ErrRedemptionNotFound)
+	}
+	if err != nil {
+		return nil, serr.DBError("GetByID", "redemption", err)
+	}
+	return r, nil
+}
+
+// GetActiveByOrder returns the redemptions of an order that have not been reversed.
+func (s Storage) GetActiveByOrder(orderID string) ([]*Redemption, error) {
+	sqlStmt := "SELECT " + redemptionColumns + " FROM redemption WHERE order_id = $1 AND reversed_at IS NULL" +
+		" ORDER BY id"
+	rows, err := s.db.Query(sqlStmt, orderID)
+	if err != nil {
+		return nil, serr.DBError("GetActiveByOrder", "redemption", err)
+	}
+	defer rows.Close()
+	redemptions := make([]*Redemption, 0)
+	for rows.Next() {
+		r, err := s.scanRedemption(rows)
+		if err != nil {
+			return nil, serr.DBError("GetActiveByOrder", "redemption", err)
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, nil
+}
+
+// MarkReversed marks the redemption as reversed. It fails if the redemption has already been reversed,
+// so a redemption can only be reversed once even when reversals race.
+func (s Storage) MarkReversed(r *Redemption) error {
+	sqlStmt := "UPDATE redemption SET reversed_at = now() WHERE id = $1 AND reversed_at IS NULL RETURNING reversed_at"
+	err := s.db.QueryRow(sqlStmt, r.ID).Scan(&r.ReversedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return serr.ValidationErr("id", "redemption has already been reversed", serr.ErrRedemptionAlreadyReversed)
+	}
+	return err
+}
+
+// UnmarkReversed undoes MarkReversed when the usage could not be restored.
+func (s Storage) UnmarkReversed(r *Redemption) error {
+	_, err := s.db.Exec("UPDATE redemption SET reversed_at = NULL WHERE id = $1", r.ID)
+	if err != nil {
+		return err
+	}
+	r.ReversedAt = nil
+	return nil
+}
+
+// GetAllByCode returns the redemptions of a code page by page, newest first.
+func (s Storage) GetAllByCode(code string, limit, offset int, count bool) ([]*Redemption, int, error) {
+	return s.getAllByPage("code = $1", code, limit, offset, count)
+}
+
+// GetAllByUser returns the redemptions made by a user page by page, newest first.
+func (s Storage) GetAllByUser(userID string, limit, offset int, count bool) ([]*Redemption, int, error) {
+	return s.getAllByPage("user_id = $1", userID, limit, offset, count)
+}
+
+func (s Storage) getAllByPage(where string, arg any, limit, offset int, count bool) ([]*Redemption, int, error) {
+	var total int
+	if count {
+		err := s.db.QueryRow("SELECT count(*) FROM redemption WHERE "+where, arg).Scan(&total)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "redemption", err)
+		}
+	}
+	pagination := " LIMIT $2 OFFSET $3"
+	order := " ORDER BY created_at DESC"
+	rows, err := s.db.Query("SELECT "+redemptionColumns+" FROM redemption WHERE "+where+order+pagination,
+		arg, limit, offset)
+	if err != nil {
+		return nil, 0, serr.DBError("List", "redemption", err)
+	}
+	defer rows.Close()
+	redemptions := make([]*Redemption, 0)
+	for rows.Next() {
+		r, err := s.scanRedemption(rows)
+		if err != nil {
+			return nil, 0, serr.DBError("List", "redemption", err)
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, total, nil
+}
+
+// ForEach calls fn for every redemption matching the filter, oldest first. The redemptions are read from a cursor
+// one by one, so any number of them can be gone through.
+func (s Storage) ForEach(f *Filter, fn func(r *Redemption) error) error {
+	c, err := f.conditions()
+	if err != nil {
+		return err
+	}
+	rows, err := s.db.Query("SELECT "+redemptionColumns+" FROM redemption"+c.Where()+" ORDER BY id", c.Args()...)
+	if err != nil {
+		return serr.DBError("ForEach", "redemption", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := s.scanRedemption(rows)
+		if err != nil {
+			return serr.DBError("ForEach", "redemption", err)
+		}
+		if err = fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (f *Filter) conditions() (*db.Conditions, error) {
+	c := &db.Conditions{}
+	if !f.From.IsZero() {
+		c.Add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		c.Add("created_at < ?", f.To)
+	}
+	if f.Prefix != "" {
+		c.Add("code LIKE ?", db.LikePrefix(f.Prefix))
+	}
+	if f.CodeType != "" {
+		c.Add("code_type = ?", f.CodeType)
+	}
+	switch f.Status {
+	case "":
+	case StatusActive:
+		c.Add("reversed_at IS NULL")
+	case StatusReversed:
+		c.Add("reversed_at IS NOT NULL")
+	default:
+		return nil, serr.ValidationErr("status", "invalid status", serr.ErrInvalidFilter)
+	}
+	return c, nil
+}
+
+func (s Storage) scanRedemption(scanner db.Scanner) (*Redemption, error) {
+	r := &Redemption{}
+	err := scanner.Scan(&r.ID, &r.Code, &r.CodeType, &r.UserID, &r.OrderID, &r.Amount, &r.CreatedAt, &r.ReversedAt)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}